@@ -16,8 +16,14 @@ import (
 	"todo-agent-backend/internal/middleware"
 	"todo-agent-backend/internal/repository"
 	"todo-agent-backend/internal/service"
+	"todo-agent-backend/pkg/cache"
 	"todo-agent-backend/pkg/gemini"
+	"todo-agent-backend/pkg/openai"
+	"todo-agent-backend/pkg/prompt"
+	"todo-agent-backend/pkg/retry"
+	"todo-agent-backend/pkg/storage"
 	"todo-agent-backend/pkg/supabase"
+	"todo-agent-backend/pkg/tesseract"
 
 	"github.com/gin-gonic/gin"
 )
@@ -36,18 +42,97 @@ func main() {
 	logger.Info("Starting Todo Agent Backend Server")
 
 	// Initialize external services
-	geminiClient := gemini.NewClient(cfg.Gemini.APIKey, cfg.Gemini.Model)
-	supabaseClient := supabase.NewClient(cfg.Supabase.URL, cfg.Supabase.Key)
+	extractor := newTodoExtractor(cfg)
+	supabaseClient := supabase.NewClient(
+		cfg.Supabase.URL,
+		cfg.Supabase.Key,
+		time.Duration(cfg.Supabase.Timeout)*time.Second,
+		retry.NewPolicy(cfg.Supabase.MaxRetries),
+	)
 
 	// Initialize repository
 	todoRepo := repository.NewTodoRepository(supabaseClient)
 
 	// Initialize services
-	processingService := service.NewProcessingService(geminiClient, todoRepo, logger)
-	jobService := service.NewJobService(logger)
+	jobStore, err := newJobStore(cfg.JobStore, logger)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to initialize job store: %v", err))
+	}
+	jobService := service.NewJobService(jobStore, logger)
+	jobService.SetIdempotencyWindow(time.Duration(cfg.Idempotency.Window) * time.Second)
+	processingService := service.NewProcessingService(extractor, todoRepo, jobService, logger)
+	if cfg.OCR.Enabled {
+		ocrClient := tesseract.NewClient(
+			cfg.OCR.TesseractPath,
+			cfg.OCR.Language,
+			cfg.OCR.TempDir,
+			time.Duration(cfg.OCR.Timeout)*time.Second,
+		)
+		processingService.SetOCR(ocrClient, service.OCRStrategy(cfg.OCR.Strategy))
+		logger.Info(fmt.Sprintf("OCR enabled with strategy %s", cfg.OCR.Strategy))
+	}
+
+	prompts, err := newPromptRegistry(cfg.Prompts)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to load prompt templates: %v", err))
+	}
+	processingService.SetPrompts(prompts)
+	processingService.SetChunking(cfg.Chunking.MaxChars, *cfg.Chunking.OverlapChars, cfg.Chunking.Concurrency)
+
+	objectStore, err := newObjectStore(cfg.Objects, supabaseClient)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to initialize object storage: %v", err))
+	}
+	if objectStore != nil {
+		processingService.SetObjectStore(objectStore)
+		logger.Info(fmt.Sprintf("Storing job inputs with the %s object storage driver", cfg.Objects.Driver))
+	}
+
+	resultCache, err := newResultCache(cfg.Cache)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to initialize result cache: %v", err))
+	}
+	if resultCache != nil {
+		processingService.SetCache(resultCache)
+		logger.Info(fmt.Sprintf("Caching extraction results, up to %d entries", cfg.Cache.MaxEntries))
+	}
+
+	// Initialize worker pool
+	workerPool := service.NewWorkerPool(
+		processingService,
+		jobService,
+		logger,
+		cfg.Worker.MaxWorkers,
+		cfg.Worker.QueueSize,
+		time.Duration(cfg.Worker.JobTimeout)*time.Second,
+	)
+	workerPool.Start()
+
+	// Resume jobs left unfinished by a previous run
+	recoveryCtx, cancelRecovery := context.WithCancel(context.Background())
+	defer cancelRecovery()
+	go func() {
+		if err := workerPool.Recover(recoveryCtx, cfg.Worker.MaxAttempts); err != nil {
+			logger.Error(fmt.Sprintf("Failed to recover jobs: %v", err))
+		}
+	}()
 
 	// Initialize handlers
-	handlers := handler.NewHandler(processingService, jobService, logger, cfg.Server.APIKey)
+	handlers := handler.NewHandler(workerPool, jobService, logger, cfg.Server.APIKey, cfg.Storage.TempDir)
+	handlers.SetLanguages(prompts.Languages())
+	handlers.SetDefaultTimezone(cfg.Prompts.DefaultTimezone)
+	handlers.SetDefaultDedupMode(cfg.Dedup.DefaultMode)
+	handlers.SetCommitter(processingService)
+
+	// Start periodic cleanup of old jobs and orphaned uploads
+	janitor := service.NewJanitor(
+		jobService,
+		cfg.Storage.TempDir,
+		time.Duration(cfg.Storage.CleanupInterval)*time.Second,
+		time.Duration(cfg.Storage.MaxAge)*time.Second,
+		logger,
+	)
+	janitor.Start()
 
 	// Setup Gin router
 	if cfg.Server.Mode == "release" {
@@ -98,13 +183,112 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Keep going on error so queued jobs are still drained
 	if err := srv.Shutdown(ctx); err != nil {
-		logger.Fatal(fmt.Sprintf("Server forced to shutdown: %v", err))
+		logger.Warn(fmt.Sprintf("Server forced to shutdown: %v", err))
+	}
+
+	// Stop recovery so it does not hold up draining
+	cancelRecovery()
+
+	// Drain queued and running jobs
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), time.Duration(cfg.Worker.ShutdownTimeout)*time.Second)
+	defer drainCancel()
+
+	if err := workerPool.Shutdown(drainCtx); err != nil {
+		logger.Warn(fmt.Sprintf("Worker pool did not drain in time: %v", err))
 	}
 
+	janitor.Stop()
+
 	logger.Info("Server exited")
 }
 
+// newTodoExtractor creates the client for the configured LLM provider
+func newTodoExtractor(cfg *config.Config) service.TodoExtractor {
+	if cfg.LLM.Provider == "openai" {
+		return service.NewOpenAIExtractor(openai.NewClient(
+			cfg.OpenAI.BaseURL,
+			cfg.OpenAI.APIKey,
+			cfg.OpenAI.Model,
+			time.Duration(cfg.OpenAI.Timeout)*time.Second,
+			retry.NewPolicy(cfg.OpenAI.MaxRetries),
+		).WithMaxRepairRounds(cfg.LLM.MaxRepairRounds))
+	}
+
+	return service.NewGeminiExtractor(gemini.NewClient(
+		cfg.Gemini.APIKey,
+		cfg.Gemini.Model,
+		time.Duration(cfg.Gemini.Timeout)*time.Second,
+		retry.NewPolicy(cfg.Gemini.MaxRetries),
+	).WithMaxRepairRounds(cfg.LLM.MaxRepairRounds))
+}
+
+// newPromptRegistry loads the prompt templates listed in config
+func newPromptRegistry(cfg config.PromptsConfig) (*prompt.Registry, error) {
+	if len(cfg.Templates) == 0 {
+		return prompt.DefaultRegistry(), nil
+	}
+
+	templates := make([]*prompt.Template, 0, len(cfg.Templates))
+	for _, t := range cfg.Templates {
+		tmpl, err := prompt.Load(prompt.Meta{
+			Name:     t.Name,
+			Version:  t.Version,
+			Language: t.Language,
+			Locale:   t.Locale,
+		}, t.Path)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+
+	return prompt.NewRegistry(cfg.DefaultLanguage, templates...)
+}
+
+// newObjectStore creates the object store selected in config, or nil when inputs are not kept
+func newObjectStore(cfg config.ObjectsConfig, client *supabase.Client) (service.ObjectStore, error) {
+	switch cfg.Driver {
+	case "local":
+		return storage.NewLocal(cfg.Path, cfg.BaseURL)
+	case "supabase":
+		return storage.NewSupabase(client, cfg.Bucket, cfg.Public), nil
+	default:
+		return nil, nil
+	}
+}
+
+// newResultCache creates the extraction result cache, or nil when it is
+// disabled. Entries live in memory and, with a disk path, also on disk.
+func newResultCache(cfg config.CacheConfig) (service.ResultCache, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	ttl := time.Duration(cfg.TTL) * time.Second
+	memory := cache.NewMemory(cfg.MaxEntries, ttl)
+	if cfg.DiskPath == "" {
+		return memory, nil
+	}
+
+	disk, err := cache.NewDisk(cfg.DiskPath, cfg.MaxEntries, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return cache.Tiered{memory, disk}, nil
+}
+
+// newJobStore creates the job store selected in config
+func newJobStore(cfg config.JobStoreConfig, logger *logger.Logger) (repository.JobStore, error) {
+	switch cfg.Driver {
+	case "file":
+		return repository.NewFileJobStore(cfg.Path, logger)
+	default:
+		return repository.NewMemoryJobStore(), nil
+	}
+}
+
 func setupRoutes(router *gin.Engine, h *handler.Handler) {
 	// Health check
 	router.GET("/healthz", h.HealthCheck)
@@ -114,6 +298,7 @@ func setupRoutes(router *gin.Engine, h *handler.Handler) {
 	{
 		api.POST("/process", h.ProcessInput)
 		api.GET("/status/:job_id", h.GetJobStatus)
+		api.POST("/jobs/:job_id/commit", h.CommitJob)
 	}
 
 	// Backward compatibility - direct routes
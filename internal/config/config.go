@@ -4,19 +4,31 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
+
+	"todo-agent-backend/internal/models"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Server    ServerConfig    `yaml:"server"`
-	Gemini    GeminiConfig    `yaml:"gemini"`
-	Supabase  SupabaseConfig  `yaml:"supabase"`
-	Logger    LoggerConfig    `yaml:"logger"`
-	Worker    WorkerConfig    `yaml:"worker"`
-	RateLimit RateLimitConfig `yaml:"rate_limit"`
-	OCR       OCRConfig       `yaml:"ocr"`
-	Storage   StorageConfig   `yaml:"storage"`
+	Server      ServerConfig      `yaml:"server"`
+	LLM         LLMConfig         `yaml:"llm"`
+	Gemini      GeminiConfig      `yaml:"gemini"`
+	OpenAI      OpenAIConfig      `yaml:"openai"`
+	Prompts     PromptsConfig     `yaml:"prompts"`
+	Chunking    ChunkingConfig    `yaml:"chunking"`
+	Dedup       DedupConfig       `yaml:"dedup"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Cache       CacheConfig       `yaml:"cache"`
+	Supabase    SupabaseConfig    `yaml:"supabase"`
+	Logger      LoggerConfig      `yaml:"logger"`
+	Worker      WorkerConfig      `yaml:"worker"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	OCR         OCRConfig         `yaml:"ocr"`
+	Storage     StorageConfig     `yaml:"storage"`
+	Objects     ObjectsConfig     `yaml:"object_storage"`
+	JobStore    JobStoreConfig    `yaml:"job_store"`
 }
 
 type ServerConfig struct {
@@ -29,6 +41,11 @@ type ServerConfig struct {
 	MaxFileSize  int64  `yaml:"max_file_size"`
 }
 
+type LLMConfig struct {
+	Provider        string `yaml:"provider"`
+	MaxRepairRounds int    `yaml:"max_repair_rounds"` // negative disables repair
+}
+
 type GeminiConfig struct {
 	APIKey     string `yaml:"api_key"`
 	Model      string `yaml:"model"`
@@ -36,6 +53,56 @@ type GeminiConfig struct {
 	MaxRetries int    `yaml:"max_retries"`
 }
 
+type OpenAIConfig struct {
+	BaseURL    string `yaml:"base_url"`
+	APIKey     string `yaml:"api_key"`
+	Model      string `yaml:"model"`
+	Timeout    int    `yaml:"timeout"`
+	MaxRetries int    `yaml:"max_retries"`
+}
+
+// PromptsConfig lists the prompt templates, one per language. The
+// built-in Indonesian template is used when none are configured.
+type PromptsConfig struct {
+	DefaultLanguage string                 `yaml:"default_language"`
+	DefaultTimezone string                 `yaml:"default_timezone"` // for requests without a timezone
+	Templates       []PromptTemplateConfig `yaml:"templates"`
+}
+
+type PromptTemplateConfig struct {
+	Language string `yaml:"language"`
+	Name     string `yaml:"name"`
+	Version  string `yaml:"version"`
+	Locale   string `yaml:"locale"`
+	Path     string `yaml:"path"`
+}
+
+// ChunkingConfig controls how long text inputs are split before extraction
+type ChunkingConfig struct {
+	MaxChars     int  `yaml:"max_chars"`     // longest chunk sent to the model
+	OverlapChars *int `yaml:"overlap_chars"` // text repeated from the end of the previous chunk, 0 turns overlap off
+	Concurrency  int  `yaml:"concurrency"`   // chunks of one job extracted at once
+}
+
+// DedupConfig sets how new todos that repeat one of the user's open todos
+// are handled when a request does not choose
+type DedupConfig struct {
+	DefaultMode string `yaml:"default_mode"` // skip, merge, flag or off
+}
+
+// IdempotencyConfig sets how long an Idempotency-Key returns the job it created
+type IdempotencyConfig struct {
+	Window int `yaml:"window"` // seconds
+}
+
+// CacheConfig controls reuse of extraction results for repeated inputs
+type CacheConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	MaxEntries int    `yaml:"max_entries"`
+	TTL        int    `yaml:"ttl"`       // seconds
+	DiskPath   string `yaml:"disk_path"` // also keeps entries on disk across restarts when set
+}
+
 type SupabaseConfig struct {
 	URL        string `yaml:"url"`
 	Key        string `yaml:"key"`
@@ -49,9 +116,11 @@ type LoggerConfig struct {
 }
 
 type WorkerConfig struct {
-	MaxWorkers int `yaml:"max_workers"`
-	QueueSize  int `yaml:"queue_size"`
-	JobTimeout int `yaml:"job_timeout"`
+	MaxWorkers      int `yaml:"max_workers"`
+	QueueSize       int `yaml:"queue_size"`
+	JobTimeout      int `yaml:"job_timeout"`
+	ShutdownTimeout int `yaml:"shutdown_timeout"`
+	MaxAttempts     int `yaml:"max_attempts"`
 }
 
 type RateLimitConfig struct {
@@ -64,6 +133,9 @@ type OCRConfig struct {
 	Enabled       bool   `yaml:"enabled"`
 	TesseractPath string `yaml:"tesseract_path"`
 	TempDir       string `yaml:"temp_dir"`
+	Language      string `yaml:"language"`
+	Timeout       int    `yaml:"timeout"`
+	Strategy      string `yaml:"strategy"`
 }
 
 type StorageConfig struct {
@@ -72,6 +144,20 @@ type StorageConfig struct {
 	MaxAge          int    `yaml:"max_age"`
 }
 
+// ObjectsConfig selects where copies of uploaded inputs are kept
+type ObjectsConfig struct {
+	Driver  string `yaml:"driver"`   // none, local or supabase
+	Path    string `yaml:"path"`     // local only
+	BaseURL string `yaml:"base_url"` // local only, file:// URLs when empty
+	Bucket  string `yaml:"bucket"`   // supabase only
+	Public  bool   `yaml:"public"`   // supabase only, whether the bucket allows anonymous downloads
+}
+
+type JobStoreConfig struct {
+	Driver string `yaml:"driver"`
+	Path   string `yaml:"path"`
+}
+
 // Load reads configuration from config.yaml file and environment variables
 func Load() (*Config, error) {
 	configPath := getConfigPath()
@@ -89,6 +175,8 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	applyDefaults(&config)
+
 	// Validate required fields
 	if err := validate(&config); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
@@ -111,13 +199,161 @@ func expandEnvVars(content string) string {
 	})
 }
 
+// applyDefaults fills in values for optional settings that were left empty
+// and lower-cases the settings that name an option
+func applyDefaults(config *Config) {
+	// The rest of the server compares these case-sensitively
+	config.LLM.Provider = strings.ToLower(config.LLM.Provider)
+	config.Dedup.DefaultMode = strings.ToLower(config.Dedup.DefaultMode)
+	config.JobStore.Driver = strings.ToLower(config.JobStore.Driver)
+	config.Objects.Driver = strings.ToLower(config.Objects.Driver)
+	config.OCR.Strategy = strings.ToLower(config.OCR.Strategy)
+
+	if config.LLM.Provider == "" {
+		config.LLM.Provider = "gemini"
+	}
+
+	if config.Prompts.DefaultLanguage == "" {
+		config.Prompts.DefaultLanguage = "id"
+	}
+
+	if config.Prompts.DefaultTimezone == "" {
+		config.Prompts.DefaultTimezone = "UTC"
+	}
+
+	if config.LLM.MaxRepairRounds == 0 {
+		config.LLM.MaxRepairRounds = 2
+	}
+
+	if config.Chunking.MaxChars <= 0 {
+		config.Chunking.MaxChars = 12000
+	}
+
+	if config.Chunking.OverlapChars == nil {
+		overlap := 600
+		config.Chunking.OverlapChars = &overlap
+	}
+
+	if config.Chunking.Concurrency <= 0 {
+		config.Chunking.Concurrency = 3
+	}
+
+	if config.Dedup.DefaultMode == "" {
+		config.Dedup.DefaultMode = models.DedupModeOff
+	}
+
+	if config.Idempotency.Window <= 0 {
+		config.Idempotency.Window = 86400
+	}
+
+	if config.Cache.MaxEntries <= 0 {
+		config.Cache.MaxEntries = 1000
+	}
+
+	if config.Cache.TTL <= 0 {
+		config.Cache.TTL = 86400
+	}
+
+	if config.Worker.MaxWorkers <= 0 {
+		config.Worker.MaxWorkers = 5
+	}
+
+	if config.Worker.QueueSize <= 0 {
+		config.Worker.QueueSize = 100
+	}
+
+	if config.Worker.JobTimeout <= 0 {
+		config.Worker.JobTimeout = 300
+	}
+
+	if config.Worker.ShutdownTimeout <= 0 {
+		config.Worker.ShutdownTimeout = 30
+	}
+
+	if config.Worker.MaxAttempts <= 0 {
+		config.Worker.MaxAttempts = 3
+	}
+
+	if config.Storage.TempDir == "" {
+		config.Storage.TempDir = "/tmp/todo-agent"
+	}
+
+	if config.Storage.CleanupInterval <= 0 {
+		config.Storage.CleanupInterval = 3600
+	}
+
+	if config.Storage.MaxAge <= 0 {
+		config.Storage.MaxAge = 86400
+	}
+
+	if config.JobStore.Driver == "" {
+		config.JobStore.Driver = "memory"
+	}
+
+	if config.OCR.Language == "" {
+		config.OCR.Language = "eng"
+	}
+
+	if config.OCR.Timeout <= 0 {
+		config.OCR.Timeout = 60
+	}
+
+	if config.Objects.Driver == "" {
+		config.Objects.Driver = "none"
+	}
+
+	if config.OCR.Strategy == "" {
+		config.OCR.Strategy = "ocr_then_vision"
+	}
+}
+
 func validate(config *Config) error {
 	if config.Server.Port <= 0 {
 		return fmt.Errorf("server port must be positive")
 	}
 
-	if config.Gemini.APIKey == "" {
-		return fmt.Errorf("gemini API key is required")
+	// Validate LLM provider
+	switch config.LLM.Provider {
+	case "gemini":
+		if config.Gemini.APIKey == "" {
+			return fmt.Errorf("gemini API key is required")
+		}
+	case "openai":
+		if config.OpenAI.BaseURL == "" {
+			return fmt.Errorf("openai base URL is required")
+		}
+		if config.OpenAI.Model == "" {
+			return fmt.Errorf("openai model is required")
+		}
+	default:
+		return fmt.Errorf("invalid LLM provider: %s", config.LLM.Provider)
+	}
+
+	// Validate prompt templates
+	var languages []string
+	for _, t := range config.Prompts.Templates {
+		if t.Language == "" || t.Name == "" || t.Path == "" {
+			return fmt.Errorf("prompt templates need a language, name and path")
+		}
+		languages = append(languages, t.Language)
+	}
+
+	if len(languages) > 0 && !contains(languages, config.Prompts.DefaultLanguage) {
+		return fmt.Errorf("no prompt template for default language: %s", config.Prompts.DefaultLanguage)
+	}
+
+	if _, err := time.LoadLocation(config.Prompts.DefaultTimezone); err != nil {
+		return fmt.Errorf("invalid default timezone: %s", config.Prompts.DefaultTimezone)
+	}
+
+	// Validate chunking
+	if overlap := *config.Chunking.OverlapChars; overlap < 0 || overlap*2 >= config.Chunking.MaxChars {
+		return fmt.Errorf("chunk overlap must be at least 0 and less than half of max_chars")
+	}
+
+	validDedupModes := []string{models.DedupModeSkip, models.DedupModeMerge, models.DedupModeFlag, models.DedupModeOff}
+	if !contains(validDedupModes, config.Dedup.DefaultMode) {
+		return fmt.Errorf("invalid dedup default mode: %s", config.Dedup.DefaultMode)
 	}
 
 	if config.Supabase.URL == "" {
@@ -144,6 +380,40 @@ func validate(config *Config) error {
 		return fmt.Errorf("invalid log format: %s", config.Logger.Format)
 	}
 
+	// Validate job store
+	validDrivers := []string{"memory", "file"}
+	if !contains(validDrivers, config.JobStore.Driver) {
+		return fmt.Errorf("invalid job store driver: %s", config.JobStore.Driver)
+	}
+
+	if config.JobStore.Driver == "file" && config.JobStore.Path == "" {
+		return fmt.Errorf("job store path is required for the file driver")
+	}
+
+	// Validate object storage
+	validObjectDrivers := []string{"none", "local", "supabase"}
+	if !contains(validObjectDrivers, config.Objects.Driver) {
+		return fmt.Errorf("invalid object storage driver: %s", config.Objects.Driver)
+	}
+
+	if config.Objects.Driver == "local" && config.Objects.Path == "" {
+		return fmt.Errorf("object storage path is required for the local driver")
+	}
+
+	if config.Objects.Driver == "supabase" && config.Objects.Bucket == "" {
+		return fmt.Errorf("object storage bucket is required for the supabase driver")
+	}
+
+	// Validate OCR
+	validStrategies := []string{"ocr_only", "vision_only", "ocr_then_vision"}
+	if !contains(validStrategies, config.OCR.Strategy) {
+		return fmt.Errorf("invalid OCR strategy: %s", config.OCR.Strategy)
+	}
+
+	if config.OCR.Enabled && config.OCR.TesseractPath == "" {
+		return fmt.Errorf("tesseract path is required when OCR is enabled")
+	}
+
 	return nil
 }
 
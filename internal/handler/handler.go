@@ -1,12 +1,18 @@
 package handler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"todo-agent-backend/internal/logger"
 	"todo-agent-backend/internal/models"
@@ -18,22 +24,55 @@ import (
 	"go.uber.org/zap"
 )
 
+// queueFullRetryAfter is the Retry-After hint (in seconds) sent when the job queue is full
+const queueFullRetryAfter = 30
+
+// maxIdempotencyKeyLength limits the Idempotency-Key header
+const maxIdempotencyKeyLength = 255
+
 type Handler struct {
-	processingService service.ProcessingServiceInterface
-	jobService        service.JobServiceInterface
-	logger            *logger.Logger
-	apiKey            string
+	jobQueue   service.JobQueueInterface
+	jobService service.JobServiceInterface
+	logger     *logger.Logger
+	apiKey     string
+	tempDir    string
+	languages  []string
+	timezone   string
+	dedupMode  string
+	committer  service.JobCommitter
 }
 
-func NewHandler(processingService service.ProcessingServiceInterface, jobService service.JobServiceInterface, logger *logger.Logger, apiKey string) *Handler {
+func NewHandler(jobQueue service.JobQueueInterface, jobService service.JobServiceInterface, logger *logger.Logger, apiKey, tempDir string) *Handler {
 	return &Handler{
-		processingService: processingService,
-		jobService:        jobService,
-		logger:            logger,
-		apiKey:            apiKey,
+		jobQueue:   jobQueue,
+		jobService: jobService,
+		logger:     logger,
+		apiKey:     apiKey,
+		tempDir:    tempDir,
 	}
 }
 
+// SetLanguages restricts the language form field to languages with a prompt template
+func (h *Handler) SetLanguages(languages []string) {
+	h.languages = languages
+}
+
+// SetDefaultTimezone sets the IANA timezone used for jobs that do not send one
+func (h *Handler) SetDefaultTimezone(timezone string) {
+	h.timezone = timezone
+}
+
+// SetDefaultDedupMode sets how jobs that do not send a dedup_mode handle
+// todos the user already has
+func (h *Handler) SetDefaultDedupMode(mode string) {
+	h.dedupMode = mode
+}
+
+// SetCommitter enables committing the todos of jobs processed in preview mode
+func (h *Handler) SetCommitter(committer service.JobCommitter) {
+	h.committer = committer
+}
+
 // HealthCheck handles GET /healthz
 func (h *Handler) HealthCheck(c *gin.Context) {
 	response := models.HealthResponse{
@@ -66,8 +105,13 @@ func (h *Handler) ProcessInput(c *gin.Context) {
 
 	// Extract form data
 	request := models.ProcessRequest{
-		Type:   c.PostForm("type"),
-		UserID: c.PostForm("user_id"),
+		Type:          c.PostForm("type"),
+		UserID:        c.PostForm("user_id"),
+		Language:      strings.ToLower(strings.TrimSpace(c.PostForm("language"))),
+		Timezone:      strings.TrimSpace(c.PostForm("timezone")),
+		ReferenceTime: strings.TrimSpace(c.PostForm("reference_time")),
+		DedupMode:     strings.ToLower(strings.TrimSpace(c.PostForm("dedup_mode"))),
+		Mode:          strings.ToLower(strings.TrimSpace(c.PostForm("mode"))),
 	}
 
 	// Validate required fields
@@ -90,9 +134,87 @@ func (h *Handler) ProcessInput(c *gin.Context) {
 		return
 	}
 
+	// Validate language
+	if request.Language != "" && len(h.languages) > 0 && !contains(h.languages, request.Language) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "validation_error",
+			Message: fmt.Sprintf("language must be one of: %s", strings.Join(h.languages, ", ")),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	// Validate idempotency key
+	idempotencyKey := strings.TrimSpace(c.GetHeader("Idempotency-Key"))
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "validation_error",
+			Message: fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	// Validate dedup mode
+	if request.DedupMode == "" {
+		request.DedupMode = h.dedupMode
+	}
+	if request.DedupMode != "" && !isValidDedupMode(request.DedupMode) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "validation_error",
+			Message: "dedup_mode must be one of: skip, merge, flag, off",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	// Validate mode
+	if request.Mode == "" {
+		request.Mode = models.JobModeSave
+	}
+	if !isValidMode(request.Mode) || (request.Mode == models.JobModePreview && h.committer == nil) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "validation_error",
+			Message: "mode must be one of: save, preview",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	// Validate timezone and reference time
+	if request.Timezone == "" {
+		request.Timezone = h.timezone
+	}
+	if request.Timezone != "" {
+		if _, err := time.LoadLocation(request.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "validation_error",
+				Message: "timezone must be an IANA timezone name such as Asia/Jakarta",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	var referenceTime *time.Time
+	if request.ReferenceTime != "" {
+		parsed, err := time.Parse(time.RFC3339, request.ReferenceTime)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "validation_error",
+				Message: "reference_time must be an RFC 3339 timestamp such as 2025-07-15T09:00:00+07:00",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+		referenceTime = &parsed
+	}
+
 	// Handle different input types
 	var content string
 	var filePath string
+	var fileName string
+	var fileDigest string
 
 	switch request.Type {
 	case "text":
@@ -129,7 +251,8 @@ func (h *Handler) ProcessInput(c *gin.Context) {
 		}
 
 		// Save file temporarily
-		filePath, err = h.saveUploadedFile(file, header)
+		fileName = filepath.Base(header.Filename)
+		filePath, fileDigest, err = h.saveUploadedFile(file, header)
 		if err != nil {
 			h.logger.Error("Failed to save uploaded file", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -143,19 +266,34 @@ func (h *Handler) ProcessInput(c *gin.Context) {
 
 	// Create job
 	job := &models.Job{
-		ID:       uuid.New().String(),
-		UserID:   request.UserID,
-		Type:     request.Type,
-		Content:  content,
-		FilePath: filePath,
-		Status:   models.JobStatusPending,
-		CreatedAt: utils.TimeNow(),
-		UpdatedAt: utils.TimeNow(),
-	}
-
-	// Submit job for processing
-	err = h.jobService.SubmitJob(job)
-	if err != nil {
+		ID:            uuid.New().String(),
+		UserID:        request.UserID,
+		Type:          request.Type,
+		Content:       content,
+		FilePath:      filePath,
+		Language:      request.Language,
+		Timezone:      request.Timezone,
+		ReferenceTime: referenceTime,
+		DedupMode:     request.DedupMode,
+		Mode:          request.Mode,
+		Status:        models.JobStatusPending,
+		CreatedAt:     utils.TimeNow(),
+		UpdatedAt:     utils.TimeNow(),
+	}
+
+	// Submit job for processing. A retried request gets the job created
+	// by its first attempt.
+	if idempotencyKey != "" {
+		job.IdempotencyKey = idempotencyKey
+		job.PayloadHash = payloadHash(request, content, fileName, fileDigest)
+
+		original, err := h.jobService.SubmitIdempotentJob(job)
+		if original != nil || err != nil {
+			h.removeUpload(filePath)
+			h.replayJob(c, original, err)
+			return
+		}
+	} else if err := h.jobService.SubmitJob(job); err != nil {
 		h.logger.Error("Failed to submit job", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
 			Error:   "internal_error",
@@ -165,8 +303,11 @@ func (h *Handler) ProcessInput(c *gin.Context) {
 		return
 	}
 
-	// Start processing asynchronously
-	go h.processingService.ProcessJob(job)
+	// Queue job for processing
+	if err := h.jobQueue.Enqueue(job); err != nil {
+		h.rejectJob(c, job, err)
+		return
+	}
 
 	// Return job ID
 	response := models.ProcessResponse{
@@ -222,7 +363,78 @@ func (h *Handler) GetJobStatus(c *gin.Context) {
 		return
 	}
 
-	// Build response
+	c.JSON(http.StatusOK, jobStatus(job))
+}
+
+// CommitJob handles POST /jobs/:job_id/commit
+func (h *Handler) CommitJob(c *gin.Context) {
+	// Authenticate request
+	if !h.authenticate(c) {
+		return
+	}
+
+	if h.committer == nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "not_found",
+			Message: "Preview mode is not enabled",
+			Code:    http.StatusNotFound,
+		})
+		return
+	}
+
+	var request models.CommitRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Request body must be a JSON object with a todos list",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	jobID := c.Param("job_id")
+	job, err := h.committer.CommitJob(c.Request.Context(), jobID, request.Todos)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Error:   "not_found",
+				Message: "Job not found",
+				Code:    http.StatusNotFound,
+			})
+
+		case errors.Is(err, service.ErrNoPreview), errors.Is(err, service.ErrCommitInProgress):
+			c.JSON(http.StatusConflict, models.ErrorResponse{
+				Error:   "conflict",
+				Message: err.Error(),
+				Code:    http.StatusConflict,
+			})
+
+		case errors.Is(err, service.ErrInvalidTodos):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "validation_error",
+				Message: err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+
+		default:
+			h.logger.Error("Failed to commit job",
+				zap.String("job_id", jobID),
+				zap.Error(err))
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error:   "internal_error",
+				Message: "Failed to save todos",
+				Code:    http.StatusInternalServerError,
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, jobStatus(job))
+}
+
+// jobStatus builds the status response of a job
+func jobStatus(job *models.Job) models.JobStatus {
 	status := models.JobStatus{
 		JobID:     job.ID,
 		Status:    string(job.Status),
@@ -234,31 +446,117 @@ func (h *Handler) GetJobStatus(c *gin.Context) {
 		status.Message = job.Error
 	}
 
+	// Chunk counts let clients show progress through long documents
+	if job.Progress.ChunksTotal > 0 {
+		progress := job.Progress
+		status.Progress = &progress
+	}
+
 	if job.Result != nil {
 		// Convert processing result to todos
-		todos := make([]models.Todo, len(job.Result.Todos))
-		for i, item := range job.Result.Todos {
-			todo := models.Todo{
-				ID:          uuid.New(),
-				UserID:      job.UserID,
-				Title:       item.Title,
-				Description: &item.Description,
-				SourceType:  job.Type,
-				CreatedAt:   job.CreatedAt,
-			}
-
-			if item.DueDate != nil && *item.DueDate != "" {
-				if dueDate, err := utils.ParseDate(*item.DueDate); err == nil {
-					todo.DueDate = &dueDate
-				}
-			}
-
-			todos[i] = todo
+		status.Todos = service.BuildTodos(job, job.Result, job.CreatedAt)
+		status.PageCount = job.Result.PageCount
+		status.PromptTemplate = job.Result.PromptTemplate
+		status.PromptVersion = job.Result.PromptVersion
+		status.Duplicates = job.Result.Duplicates
+		status.CacheHit = job.Result.CacheHit
+		status.Preview = job.Result.Preview
+		status.CommittedAt = job.Result.CommittedAt
+
+		// Clients edit these and send them to the commit endpoint
+		if job.Result.Preview {
+			status.Items = job.Result.Todos
 		}
-		status.Todos = todos
 	}
 
-	c.JSON(http.StatusOK, status)
+	return status
+}
+
+// rejectJob fails a job that could not be queued and writes the error response
+func (h *Handler) rejectJob(c *gin.Context, job *models.Job, err error) {
+	h.logger.Warn("Failed to queue job",
+		zap.String("job_id", job.ID),
+		zap.Error(err))
+
+	if updateErr := h.jobService.UpdateJob(job.ID, models.JobStatusFailed, nil, err.Error()); updateErr != nil {
+		h.logger.Error("Failed to mark rejected job as failed", zap.Error(updateErr))
+	}
+
+	h.removeUpload(job.FilePath)
+
+	if errors.Is(err, service.ErrQueueFull) || errors.Is(err, service.ErrShuttingDown) {
+		c.Header("Retry-After", strconv.Itoa(queueFullRetryAfter))
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "service_unavailable",
+			Message: "Server is busy, please retry later",
+			Code:    http.StatusServiceUnavailable,
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+		Error:   "internal_error",
+		Message: "Failed to queue job for processing",
+		Code:    http.StatusInternalServerError,
+	})
+}
+
+// replayJob answers a request whose idempotency key was already used
+// with the job created by the first request, or explains why it cannot
+func (h *Handler) replayJob(c *gin.Context, original *models.Job, err error) {
+	switch {
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		c.JSON(http.StatusUnprocessableEntity, models.ErrorResponse{
+			Error:   "idempotency_key_reused",
+			Message: "Idempotency-Key was already used with a different request",
+			Code:    http.StatusUnprocessableEntity,
+		})
+
+	case err != nil:
+		h.logger.Error("Failed to submit job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "internal_error",
+			Message: "Failed to submit job for processing",
+			Code:    http.StatusInternalServerError,
+		})
+
+	default:
+		h.logger.Info("Replaying job for repeated request",
+			zap.String("job_id", original.ID),
+			zap.String("user_id", original.UserID))
+
+		c.Header("Idempotent-Replayed", "true")
+		c.JSON(http.StatusOK, models.ProcessResponse{
+			JobID:   original.ID,
+			Status:  string(original.Status),
+			Message: "Request already received",
+		})
+	}
+}
+
+// removeUpload deletes a saved upload that no job will process
+func (h *Handler) removeUpload(filePath string) {
+	if filePath == "" {
+		return
+	}
+	if err := os.Remove(filePath); err != nil {
+		h.logger.Warn("Failed to remove uploaded file", zap.String("file_path", filePath), zap.Error(err))
+	}
+}
+
+// payloadHash fingerprints a request so a reused idempotency key can be
+// told apart from a retry of the same request. Uploads are represented by
+// their name and the SHA-256 of their content.
+func payloadHash(request models.ProcessRequest, content, fileName, fileDigest string) string {
+	hash := sha256.New()
+	for _, field := range []string{
+		request.Type, request.UserID, request.Language, request.Timezone,
+		request.ReferenceTime, request.DedupMode, request.Mode, content, fileName, fileDigest,
+	} {
+		// Length prefixes keep field boundaries unambiguous
+		fmt.Fprintf(hash, "%d:%s;", len(field), field)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
 }
 
 // authenticate validates API key
@@ -294,6 +592,15 @@ func isValidType(inputType string) bool {
 	return false
 }
 
+func isValidDedupMode(mode string) bool {
+	validModes := []string{models.DedupModeSkip, models.DedupModeMerge, models.DedupModeFlag, models.DedupModeOff}
+	return contains(validModes, mode)
+}
+
+func isValidMode(mode string) bool {
+	return mode == models.JobModeSave || mode == models.JobModePreview
+}
+
 // validateFile validates uploaded file
 func (h *Handler) validateFile(header *multipart.FileHeader, inputType string) error {
 	// Check file size (5MB max)
@@ -312,41 +619,45 @@ func (h *Handler) validateFile(header *multipart.FileHeader, inputType string) e
 			return fmt.Errorf("invalid image format. Supported: jpg, jpeg, png, gif, bmp, webp")
 		}
 	case "document":
-		validExts := []string{".pdf", ".doc", ".docx", ".txt", ".rtf"}
+		if ext == ".doc" {
+			return fmt.Errorf("legacy .doc files are not supported. Save the document as docx or pdf and upload it again")
+		}
+		validExts := []string{".pdf", ".docx", ".txt", ".rtf"}
 		if !contains(validExts, ext) {
-			return fmt.Errorf("invalid document format. Supported: pdf, doc, docx, txt, rtf")
+			return fmt.Errorf("invalid document format. Supported: pdf, docx, txt, rtf")
 		}
 	}
 
 	return nil
 }
 
-// saveUploadedFile saves uploaded file to temporary directory
-func (h *Handler) saveUploadedFile(file multipart.File, header *multipart.FileHeader) (string, error) {
+// saveUploadedFile saves uploaded file to temporary directory and returns
+// its path and the hex SHA-256 of its content
+func (h *Handler) saveUploadedFile(file multipart.File, header *multipart.FileHeader) (string, string, error) {
 	// Create temp directory if not exists
-	tempDir := "/tmp/todo-agent"
-	if err := utils.CreateDirIfNotExists(tempDir); err != nil {
-		return "", err
+	if err := utils.CreateDirIfNotExists(h.tempDir); err != nil {
+		return "", "", err
 	}
 
 	// Generate unique filename
-	filename := fmt.Sprintf("%s_%s", uuid.New().String(), header.Filename)
-	filePath := filepath.Join(tempDir, filename)
+	filename := fmt.Sprintf("%s_%s", uuid.New().String(), filepath.Base(header.Filename))
+	filePath := filepath.Join(h.tempDir, filename)
 
 	// Create destination file
 	dst, err := utils.CreateFile(filePath)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	defer dst.Close()
 
-	// Copy file content
-	_, err = io.Copy(dst, file)
+	// Copy file content, hashing it on the way
+	digest := sha256.New()
+	_, err = io.Copy(io.MultiWriter(dst, digest), file)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return filePath, nil
+	return filePath, hex.EncodeToString(digest.Sum(nil)), nil
 }
 
 // contains checks if slice contains item
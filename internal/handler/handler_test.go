@@ -2,27 +2,37 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"todo-agent-backend/internal/logger"
 	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+	"todo-agent-backend/internal/service"
+	"todo-agent-backend/pkg/retry"
+	"todo-agent-backend/pkg/supabase"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-// MockProcessingService for testing
-type MockProcessingService struct {
+// MockJobQueue for testing
+type MockJobQueue struct {
 	mock.Mock
 }
 
-func (m *MockProcessingService) ProcessJob(job *models.Job) {
-	m.Called(job)
+func (m *MockJobQueue) Enqueue(job *models.Job) error {
+	args := m.Called(job)
+	return args.Error(0)
 }
 
 // MockJobService for testing
@@ -35,6 +45,14 @@ func (m *MockJobService) SubmitJob(job *models.Job) error {
 	return args.Error(0)
 }
 
+func (m *MockJobService) SubmitIdempotentJob(job *models.Job) (*models.Job, error) {
+	args := m.Called(job)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
 func (m *MockJobService) GetJob(jobID string) (*models.Job, error) {
 	args := m.Called(jobID)
 	if args.Get(0) == nil {
@@ -48,20 +66,48 @@ func (m *MockJobService) UpdateJob(jobID string, status models.JobStatusEnum, re
 	return args.Error(0)
 }
 
+func (m *MockJobService) UpdateProgress(jobID string, progress models.JobProgress) error {
+	args := m.Called(jobID, progress)
+	return args.Error(0)
+}
+
+func (m *MockJobService) UpdateResult(jobID string, result *models.ProcessingResult) error {
+	args := m.Called(jobID, result)
+	return args.Error(0)
+}
+
 func (m *MockJobService) ListJobs(userID string) []*models.Job {
 	args := m.Called(userID)
 	return args.Get(0).([]*models.Job)
 }
 
+func (m *MockJobService) ListJobsByStatus(statuses ...models.JobStatusEnum) ([]*models.Job, error) {
+	args := m.Called(statuses)
+	return args.Get(0).([]*models.Job), args.Error(1)
+}
+
+// MockJobCommitter for testing
+type MockJobCommitter struct {
+	mock.Mock
+}
+
+func (m *MockJobCommitter) CommitJob(ctx context.Context, jobID string, todos []models.TodoItem) (*models.Job, error) {
+	args := m.Called(jobID, todos)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
 func TestHealthCheck(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	
-	mockProcessingService := &MockProcessingService{}
+	mockJobQueue := &MockJobQueue{}
 	mockJobService := &MockJobService{}
 	logger := logger.NewLogger("info", "console")
 	
-	handler := NewHandler(mockProcessingService, mockJobService, logger, "test-api-key")
+	handler := NewHandler(mockJobQueue, mockJobService, logger, "test-api-key", t.TempDir())
 	
 	router := gin.New()
 	router.GET("/healthz", handler.HealthCheck)
@@ -85,15 +131,15 @@ func TestProcessInput_Text(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	
-	mockProcessingService := &MockProcessingService{}
+	mockJobQueue := &MockJobQueue{}
 	mockJobService := &MockJobService{}
 	logger := logger.NewLogger("info", "console")
 	
-	handler := NewHandler(mockProcessingService, mockJobService, logger, "test-api-key")
+	handler := NewHandler(mockJobQueue, mockJobService, logger, "test-api-key", t.TempDir())
 	
 	// Mock expectations
 	mockJobService.On("SubmitJob", mock.AnythingOfType("*models.Job")).Return(nil)
-	mockProcessingService.On("ProcessJob", mock.AnythingOfType("*models.Job")).Return()
+	mockJobQueue.On("Enqueue", mock.AnythingOfType("*models.Job")).Return(nil)
 	
 	router := gin.New()
 	router.POST("/process", handler.ProcessInput)
@@ -124,18 +170,245 @@ func TestProcessInput_Text(t *testing.T) {
 	
 	// Verify mocks
 	mockJobService.AssertExpectations(t)
-	mockProcessingService.AssertExpectations(t)
+	mockJobQueue.AssertExpectations(t)
+}
+
+func TestProcessInput_QueueFull(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	mockJobQueue := &MockJobQueue{}
+	mockJobService := &MockJobService{}
+	logger := logger.NewLogger("info", "console")
+
+	handler := NewHandler(mockJobQueue, mockJobService, logger, "test-api-key", t.TempDir())
+
+	// Mock expectations
+	mockJobService.On("SubmitJob", mock.AnythingOfType("*models.Job")).Return(nil)
+	mockJobQueue.On("Enqueue", mock.AnythingOfType("*models.Job")).Return(service.ErrQueueFull)
+	mockJobService.On("UpdateJob", mock.AnythingOfType("string"), models.JobStatusFailed, (*models.ProcessingResult)(nil), service.ErrQueueFull.Error()).Return(nil)
+
+	router := gin.New()
+	router.POST("/process", handler.ProcessInput)
+
+	// Create form data
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("type", "text")
+	writer.WriteField("content", "Send report")
+	writer.WriteField("user_id", "test-user")
+	writer.Close()
+
+	// Test
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/process", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-API-Key", "test-api-key")
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	// Verify mocks
+	mockJobService.AssertExpectations(t)
+	mockJobQueue.AssertExpectations(t)
+}
+
+func TestProcessInput_LegacyDocRejected(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	mockJobQueue := &MockJobQueue{}
+	mockJobService := &MockJobService{}
+	logger := logger.NewLogger("info", "console")
+
+	tempDir := t.TempDir()
+	handler := NewHandler(mockJobQueue, mockJobService, logger, "test-api-key", tempDir)
+
+	router := gin.New()
+	router.POST("/process", handler.ProcessInput)
+
+	// Create form data
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("type", "document")
+	writer.WriteField("user_id", "test-user")
+	part, _ := writer.CreateFormFile("file", "notes.doc")
+	part.Write([]byte{0xd0, 0xcf, 0x11, 0xe0})
+	writer.Close()
+
+	// Test
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/process", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-API-Key", "test-api-key")
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "legacy .doc files are not supported")
+
+	entries, _ := os.ReadDir(tempDir)
+	assert.Empty(t, entries)
+
+	// No job is created for a rejected upload
+	mockJobService.AssertNotCalled(t, "SubmitJob", mock.Anything)
+	mockJobQueue.AssertNotCalled(t, "Enqueue", mock.Anything)
+}
+
+func TestProcessInput_UnsupportedLanguage(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	mockJobQueue := &MockJobQueue{}
+	mockJobService := &MockJobService{}
+	logger := logger.NewLogger("info", "console")
+
+	handler := NewHandler(mockJobQueue, mockJobService, logger, "test-api-key", t.TempDir())
+	handler.SetLanguages([]string{"en", "id"})
+
+	router := gin.New()
+	router.POST("/process", handler.ProcessInput)
+
+	// Create form data
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("type", "text")
+	writer.WriteField("content", "Send report")
+	writer.WriteField("user_id", "test-user")
+	writer.WriteField("language", "fr")
+	writer.Close()
+
+	// Test
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/process", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("X-API-Key", "test-api-key")
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "language must be one of: en, id")
+	mockJobService.AssertNotCalled(t, "SubmitJob", mock.Anything)
+}
+
+func TestProcessInput_InvalidTimezone(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	mockJobQueue := &MockJobQueue{}
+	mockJobService := &MockJobService{}
+	logger := logger.NewLogger("info", "console")
+
+	handler := NewHandler(mockJobQueue, mockJobService, logger, "test-api-key", t.TempDir())
+
+	router := gin.New()
+	router.POST("/process", handler.ProcessInput)
+
+	tests := []struct {
+		field   string
+		value   string
+		message string
+	}{
+		{field: "timezone", value: "WIB", message: "timezone must be an IANA timezone name"},
+		{field: "reference_time", value: "besok", message: "reference_time must be an RFC 3339 timestamp"},
+		{field: "dedup_mode", value: "replace", message: "dedup_mode must be one of: skip, merge, flag, off"},
+	}
+
+	for _, tt := range tests {
+		// Create form data
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		writer.WriteField("type", "text")
+		writer.WriteField("content", "Send report")
+		writer.WriteField("user_id", "test-user")
+		writer.WriteField(tt.field, tt.value)
+		writer.Close()
+
+		// Test
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/process", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("X-API-Key", "test-api-key")
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), tt.message)
+	}
+	mockJobService.AssertNotCalled(t, "SubmitJob", mock.Anything)
+}
+
+func TestProcessInput_IdempotencyKey(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	mockJobQueue := &MockJobQueue{}
+	logger := logger.NewLogger("info", "console")
+	jobService := service.NewJobService(repository.NewMemoryJobStore(), logger)
+
+	handler := NewHandler(mockJobQueue, jobService, logger, "test-api-key", t.TempDir())
+
+	// Only the first request is queued
+	mockJobQueue.On("Enqueue", mock.AnythingOfType("*models.Job")).Return(nil).Once()
+
+	router := gin.New()
+	router.POST("/process", handler.ProcessInput)
+
+	send := func(userID, content string) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		writer.WriteField("type", "text")
+		writer.WriteField("content", content)
+		writer.WriteField("user_id", userID)
+		writer.Close()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/process", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("X-API-Key", "test-api-key")
+		req.Header.Set("Idempotency-Key", "retry-1")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("test-user", "Send report")
+	require.Equal(t, http.StatusAccepted, first.Code)
+	var accepted models.ProcessResponse
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &accepted))
+
+	// A retry gets the original job and its status
+	retry := send("test-user", "Send report")
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	var replayed models.ProcessResponse
+	require.NoError(t, json.Unmarshal(retry.Body.Bytes(), &replayed))
+	assert.Equal(t, accepted.JobID, replayed.JobID)
+	assert.Equal(t, "pending", replayed.Status)
+
+	// The same key with another payload is rejected
+	mismatch := send("test-user", "Buy coffee")
+	assert.Equal(t, http.StatusUnprocessableEntity, mismatch.Code)
+	assert.Contains(t, mismatch.Body.String(), "idempotency_key_reused")
+
+	// Keys are scoped per user
+	mockJobQueue.On("Enqueue", mock.AnythingOfType("*models.Job")).Return(nil).Once()
+	other := send("other-user", "Send report")
+	assert.Equal(t, http.StatusAccepted, other.Code)
+
+	mockJobQueue.AssertExpectations(t)
 }
 
 func TestProcessInput_InvalidAPIKey(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	
-	mockProcessingService := &MockProcessingService{}
+	mockJobQueue := &MockJobQueue{}
 	mockJobService := &MockJobService{}
 	logger := logger.NewLogger("info", "console")
 	
-	handler := NewHandler(mockProcessingService, mockJobService, logger, "test-api-key")
+	handler := NewHandler(mockJobQueue, mockJobService, logger, "test-api-key", t.TempDir())
 	
 	router := gin.New()
 	router.POST("/process", handler.ProcessInput)
@@ -154,11 +427,11 @@ func TestGetJobStatus(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
 	
-	mockProcessingService := &MockProcessingService{}
+	mockJobQueue := &MockJobQueue{}
 	mockJobService := &MockJobService{}
 	logger := logger.NewLogger("info", "console")
 	
-	handler := NewHandler(mockProcessingService, mockJobService, logger, "test-api-key")
+	handler := NewHandler(mockJobQueue, mockJobService, logger, "test-api-key", t.TempDir())
 	
 	// Mock job
 	job := &models.Job{
@@ -175,6 +448,7 @@ func TestGetJobStatus(t *testing.T) {
 				},
 			},
 		},
+		Progress: models.JobProgress{ChunksTotal: 3, ChunksDone: 3},
 	}
 	
 	mockJobService.On("GetJob", "test-job-id").Return(job, nil)
@@ -197,7 +471,142 @@ func TestGetJobStatus(t *testing.T) {
 	assert.Equal(t, "test-job-id", response.JobID)
 	assert.Equal(t, "completed", response.Status)
 	assert.Len(t, response.Todos, 1)
+	if assert.NotNil(t, response.Progress) {
+		assert.Equal(t, 3, response.Progress.ChunksDone)
+	}
 	
 	// Verify mocks
 	mockJobService.AssertExpectations(t)
 }
+
+func TestCommitJob(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+
+	mockJobQueue := &MockJobQueue{}
+	mockJobService := &MockJobService{}
+	mockCommitter := &MockJobCommitter{}
+	logger := logger.NewLogger("info", "console")
+
+	handler := NewHandler(mockJobQueue, mockJobService, logger, "test-api-key", t.TempDir())
+	handler.SetCommitter(mockCommitter)
+
+	todos := []models.TodoItem{{Title: "Kirim laporan"}}
+	committedAt := time.Now()
+	job := &models.Job{
+		ID:     "test-job-id",
+		UserID: "test-user",
+		Type:   "text",
+		Status: models.JobStatusCompleted,
+		Result: &models.ProcessingResult{Todos: todos, CommittedAt: &committedAt},
+	}
+
+	mockCommitter.On("CommitJob", "test-job-id", todos).Return(job, nil).Once()
+	mockCommitter.On("CommitJob", "test-job-id", todos).Return(nil, service.ErrNoPreview).Once()
+
+	router := gin.New()
+	router.POST("/jobs/:job_id/commit", handler.CommitJob)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/jobs/test-job-id/commit", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", "test-api-key")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Test
+	w := send(`{"todos": [{"title": "Kirim laporan"}]}`)
+
+	// Assert
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response models.JobStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Todos, 1)
+	assert.False(t, response.Preview)
+	assert.NotNil(t, response.CommittedAt)
+
+	// A committed job cannot be committed again
+	w = send(`{"todos": [{"title": "Kirim laporan"}]}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = send(`not json`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Verify mocks
+	mockCommitter.AssertExpectations(t)
+}
+
+func TestCommitJob_StatusItems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logger.NewLogger("error", "console")
+
+	// Supabase stub that keeps inserted todos
+	var saved []models.Todo
+	db := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var todos []models.Todo
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&todos))
+		saved = append(saved, todos...)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer db.Close()
+	todoRepo := repository.NewTodoRepository(supabase.NewClient(db.URL, "test-key", time.Second, retry.NewPolicy(0)))
+
+	jobService := service.NewJobService(repository.NewMemoryJobStore(), log)
+	ps := service.NewProcessingService(&service.FakeExtractor{}, todoRepo, jobService, log)
+
+	handler := NewHandler(&MockJobQueue{}, jobService, log, "test-api-key", t.TempDir())
+	handler.SetCommitter(ps)
+
+	router := gin.New()
+	router.GET("/status/:job_id", handler.GetJobStatus)
+	router.POST("/jobs/:job_id/commit", handler.CommitJob)
+
+	job := &models.Job{
+		ID:      "test-job-id",
+		UserID:  "test-user",
+		Type:    "text",
+		Content: "- Kirim laporan 2025-07-18\n  - Cek angka\n- Beli kopi",
+		Mode:    models.JobModePreview,
+		Status:  models.JobStatusPending,
+	}
+	require.NoError(t, jobService.SubmitJob(job))
+	ps.ProcessJob(context.Background(), job)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/status/test-job-id", nil)
+	req.Header.Set("X-API-Key", "test-api-key")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var status models.JobStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	require.True(t, status.Preview)
+	require.Len(t, status.Items, 2)
+
+	// The items go back to the commit endpoint unchanged
+	body, err := json.Marshal(models.CommitRequest{Todos: status.Items})
+	require.NoError(t, err)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/jobs/test-job-id/commit", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "test-api-key")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	require.Len(t, saved, 3)
+	assert.Equal(t, "Kirim laporan 2025-07-18", saved[0].Title)
+	assert.NotNil(t, saved[0].DueDate)
+	assert.Equal(t, "Cek angka", saved[1].Title)
+	require.NotNil(t, saved[1].ParentID)
+	assert.Equal(t, saved[0].ID, *saved[1].ParentID)
+	assert.Nil(t, saved[2].ParentID)
+
+	var committed models.JobStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &committed))
+	assert.False(t, committed.Preview)
+	assert.Empty(t, committed.Items)
+}
@@ -8,21 +8,67 @@ import (
 
 // Todo represents a todo item in the database
 type Todo struct {
-	ID          uuid.UUID  `json:"id" db:"id"`
-	UserID      string     `json:"user_id" db:"user_id"`
-	Title       string     `json:"title" db:"title"`
-	Description *string    `json:"description" db:"description"`
-	DueDate     *time.Time `json:"due_date" db:"due_date"`
-	SourceType  string     `json:"source_type" db:"source_type"`
-	SourceURL   *string    `json:"source_url" db:"source_url"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ID               uuid.UUID   `json:"id" db:"id"`
+	UserID           string      `json:"user_id" db:"user_id"`
+	Title            string      `json:"title" db:"title"`
+	Description      *string     `json:"description" db:"description"`
+	DueDate          *time.Time  `json:"due_date" db:"due_date"`
+	Priority         *string     `json:"priority" db:"priority"`
+	Tags             []string    `json:"tags" db:"tags"`
+	EstimatedMinutes *int        `json:"estimated_minutes" db:"estimated_minutes"`
+	Assignee         *string     `json:"assignee" db:"assignee"`
+	ParentID         *uuid.UUID  `json:"parent_id" db:"parent_id"`       // set for subtasks
+	DuplicateOf      *uuid.UUID  `json:"duplicate_of" db:"duplicate_of"` // existing todo this one likely repeats
+	Completed        bool        `json:"completed" db:"completed"`
+	SourceType       string      `json:"source_type" db:"source_type"`
+	SourceURL        *string     `json:"source_url" db:"source_url"`
+	SourceSpan       *SourceSpan `json:"source_span" db:"source_span"`
+	CreatedAt        time.Time   `json:"created_at" db:"created_at"`
 }
 
+// SourceSpan is the part of the input a todo was extracted from. Start and
+// End are character offsets into the extracted text and are omitted when
+// the excerpt could not be found there; Page is set for PDF input.
+type SourceSpan struct {
+	Text  string `json:"text"`
+	Page  int    `json:"page,omitempty"`
+	Start *int   `json:"start,omitempty"`
+	End   *int   `json:"end,omitempty"`
+}
+
+// TodoPatch holds the fields of an existing todo to change. Nil and empty
+// fields are left as they are.
+type TodoPatch struct {
+	Description      *string    `json:"description,omitempty"`
+	DueDate          *time.Time `json:"due_date,omitempty"`
+	Priority         *string    `json:"priority,omitempty"`
+	Tags             []string   `json:"tags,omitempty"`
+	EstimatedMinutes *int       `json:"estimated_minutes,omitempty"`
+	Assignee         *string    `json:"assignee,omitempty"`
+}
+
+// Todo priorities
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+)
+
 // ProcessRequest represents the input request for processing
 type ProcessRequest struct {
-	Type    string `form:"type" binding:"required,oneof=text image document"`
-	Content string `form:"content"`
-	UserID  string `form:"user_id" binding:"required"`
+	Type          string `form:"type" binding:"required,oneof=text image document"`
+	Content       string `form:"content"`
+	UserID        string `form:"user_id" binding:"required"`
+	Language      string `form:"language"`
+	Timezone      string `form:"timezone"`
+	ReferenceTime string `form:"reference_time"`
+	DedupMode     string `form:"dedup_mode"`
+	Mode          string `form:"mode"`
+}
+
+// CommitRequest carries the todos of a preview job to save, as edited by the user
+type CommitRequest struct {
+	Todos []TodoItem `json:"todos"`
 }
 
 // ProcessResponse represents the response from processing endpoint
@@ -34,26 +80,52 @@ type ProcessResponse struct {
 
 // JobStatus represents the status of a processing job
 type JobStatus struct {
-	JobID     string    `json:"job_id"`
-	Status    string    `json:"status"`
-	Message   string    `json:"message,omitempty"`
-	Todos     []Todo    `json:"todos,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	JobID          string          `json:"job_id"`
+	Status         string          `json:"status"`
+	Message        string          `json:"message,omitempty"`
+	Todos          []Todo          `json:"todos,omitempty"`
+	Items          []TodoItem      `json:"items,omitempty"` // editable todos of a preview, in the commit format
+	PageCount      int             `json:"page_count,omitempty"`
+	PromptTemplate string          `json:"prompt_template,omitempty"`
+	PromptVersion  string          `json:"prompt_version,omitempty"`
+	Duplicates     []DuplicateTodo `json:"duplicates,omitempty"`
+	CacheHit       bool            `json:"cache_hit,omitempty"`
+	Preview        bool            `json:"preview,omitempty"`
+	CommittedAt    *time.Time      `json:"committed_at,omitempty"`
+	Progress       *JobProgress    `json:"progress,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
 }
 
 // Job represents a processing job
 type Job struct {
-	ID        string             `json:"id"`
-	UserID    string             `json:"user_id"`
-	Type      string             `json:"type"`
-	Content   string             `json:"content"`
-	FilePath  string             `json:"file_path,omitempty"`
-	Status    JobStatusEnum      `json:"status"`
-	Result    *ProcessingResult  `json:"result,omitempty"`
-	Error     string             `json:"error,omitempty"`
-	CreatedAt time.Time          `json:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at"`
+	ID             string            `json:"id"`
+	UserID         string            `json:"user_id"`
+	Type           string            `json:"type"`
+	Content        string            `json:"content"`
+	FilePath       string            `json:"file_path,omitempty"`
+	Language       string            `json:"language,omitempty"`        // prompt language, the default when empty
+	Timezone       string            `json:"timezone,omitempty"`        // IANA name used to resolve relative dates
+	ReferenceTime  *time.Time        `json:"reference_time,omitempty"`  // "now" for relative dates, the creation time when nil
+	DedupMode      string            `json:"dedup_mode,omitempty"`      // handling of todos the user already has, off when empty
+	IdempotencyKey string            `json:"idempotency_key,omitempty"` // client key for safe retries, unique per user
+	PayloadHash    string            `json:"payload_hash,omitempty"`    // SHA-256 of the request the key was first used with
+	Mode           string            `json:"mode,omitempty"`            // preview keeps todos on the job until they are committed
+	Status         JobStatusEnum     `json:"status"`
+	Result         *ProcessingResult `json:"result,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	Attempts       int               `json:"attempts"`
+	Progress       JobProgress       `json:"progress"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// JobProgress holds counters recorded while a job is processed
+type JobProgress struct {
+	RequestAttempts int `json:"request_attempts"` // outbound API requests including retries
+	RepairRounds    int `json:"repair_rounds"`    // follow-up requests asking the model to fix invalid output
+	ChunksTotal     int `json:"chunks_total"`     // pieces a long input was split into for extraction
+	ChunksDone      int `json:"chunks_done"`      // pieces extracted so far
 }
 
 // JobStatusEnum represents possible job statuses
@@ -64,21 +136,95 @@ const (
 	JobStatusProcessing JobStatusEnum = "processing"
 	JobStatusCompleted  JobStatusEnum = "completed"
 	JobStatusFailed     JobStatusEnum = "failed"
+	JobStatusCancelled  JobStatusEnum = "cancelled"
 )
 
+// jobTransitions lists the statuses a job may move to from each status.
+// Completed, failed and cancelled are terminal.
+var jobTransitions = map[JobStatusEnum][]JobStatusEnum{
+	JobStatusPending:    {JobStatusProcessing, JobStatusFailed, JobStatusCancelled},
+	JobStatusProcessing: {JobStatusCompleted, JobStatusFailed, JobStatusCancelled, JobStatusPending},
+}
+
+// CanTransitionTo reports whether a job in status s may move to next.
+// Processing may go back to pending so interrupted jobs can be requeued.
+func (s JobStatusEnum) CanTransitionTo(next JobStatusEnum) bool {
+	for _, allowed := range jobTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether no further transitions are possible from s
+func (s JobStatusEnum) IsTerminal() bool {
+	return len(jobTransitions[s]) == 0
+}
+
 // ProcessingResult represents the result of AI processing
 type ProcessingResult struct {
-	Todos       []TodoItem `json:"todos"`
-	ProcessedAt time.Time  `json:"processed_at"`
+	Todos          []TodoItem      `json:"todos"`
+	PageCount      int             `json:"page_count,omitempty"`
+	PromptTemplate string          `json:"prompt_template,omitempty"` // name of the prompt template used
+	PromptVersion  string          `json:"prompt_version,omitempty"`
+	SourceURL      string          `json:"source_url,omitempty"`   // stored copy of the original input
+	Duplicates     []DuplicateTodo `json:"duplicates,omitempty"`   // items matching todos the user already has
+	CacheHit       bool            `json:"cache_hit"`              // todos were reused from an earlier identical input
+	Preview        bool            `json:"preview,omitempty"`      // todos are waiting to be committed and not saved yet
+	CommitID       *uuid.UUID      `json:"commit_id,omitempty"`    // set when a preview starts being committed, todo IDs derive from it
+	CommittedAt    *time.Time      `json:"committed_at,omitempty"` // when the todos of a preview were saved
+	ProcessedAt    time.Time       `json:"processed_at"`
 }
 
 // TodoItem represents a todo item from AI processing
 type TodoItem struct {
 	Title       string  `json:"title"`
 	Description string  `json:"description"`
-	DueDate     *string `json:"due_date"` // YYYY-MM-DD format or null
+	DueDate     *string `json:"due_date"`           // YYYY-MM-DD format or null
+	DueTime     *string `json:"due_time,omitempty"` // RFC 3339 with offset, only when a time of day is known
+
+	Priority         string   `json:"priority,omitempty"` // low, medium or high
+	Tags             []string `json:"tags,omitempty"`
+	EstimatedMinutes *int     `json:"estimated_minutes,omitempty"`
+	Assignee         string   `json:"assignee,omitempty"` // person responsible, as named in the input
+
+	Subtasks []TodoItem  `json:"subtasks,omitempty"` // checklist steps of this todo
+	Source   *SourceSpan `json:"source,omitempty"`   // where in the input the todo was found
+
+	ParentID    *uuid.UUID `json:"parent_id,omitempty"`    // existing todo a new subtask was merged into
+	DuplicateOf *uuid.UUID `json:"duplicate_of,omitempty"` // existing todo this item likely repeats
+}
+
+// DuplicateTodo reports an extracted item that matched one of the user's
+// open todos and what was done with it
+type DuplicateTodo struct {
+	Title       string    `json:"title"`
+	DuplicateOf uuid.UUID `json:"duplicate_of"`
+	Action      string    `json:"action"` // skipped, merged or flagged
 }
 
+// Dedup modes select how items matching the user's open todos are handled
+const (
+	DedupModeSkip  = "skip"  // leave the item out
+	DedupModeMerge = "merge" // fill in the existing todo and add new subtasks to it
+	DedupModeFlag  = "flag"  // insert the item and point it to the existing todo
+	DedupModeOff   = "off"   // insert every item
+)
+
+// Job modes
+const (
+	JobModeSave    = "save"    // save extracted todos right away
+	JobModePreview = "preview" // keep extracted todos on the job until they are committed
+)
+
+// Actions reported for duplicates
+const (
+	DuplicateSkipped = "skipped"
+	DuplicateMerged  = "merged"
+	DuplicateFlagged = "flagged"
+)
+
 // HealthResponse represents health check response
 type HealthResponse struct {
 	Status    string    `json:"status"`
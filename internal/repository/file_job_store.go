@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	jobFileExt     = ".json"
+	corruptFileExt = ".corrupt"
+)
+
+// FileJobStore persists each job as a JSON file in a directory.
+// Writes go through a temporary file and a rename so a crash never
+// leaves a partially written job behind.
+type FileJobStore struct {
+	dir    string
+	mutex  sync.RWMutex
+	logger *logger.Logger
+}
+
+// NewFileJobStore creates a file job store rooted at dir
+func NewFileJobStore(dir string, logger *logger.Logger) (*FileJobStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job store directory: %w", err)
+	}
+
+	return &FileJobStore{dir: dir, logger: logger}, nil
+}
+
+// Save stores or replaces a job
+func (s *FileJobStore) Save(job *models.Job) error {
+	path, err := s.jobPath(job.ID)
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeJob(job)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tmp, err := os.CreateTemp(s.dir, job.ID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create job file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write job file: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync job file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close job file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace job file: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves a job by ID
+func (s *FileJobStore) Get(jobID string) (*models.Job, error) {
+	path, err := s.jobPath(jobID)
+	if err != nil {
+		// No job can be stored under an invalid ID
+		return nil, ErrJobNotFound
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return readJobFile(path)
+}
+
+// List returns all stored jobs. Files that cannot be read are skipped,
+// and files that cannot be decoded are renamed with a .corrupt extension
+// so they no longer hide the other jobs.
+func (s *FileJobStore) List() ([]*models.Job, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*"+jobFileExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job files: %w", err)
+	}
+
+	jobs := make([]*models.Job, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			// The file may have been removed since the directory was listed
+			if !os.IsNotExist(err) {
+				s.logger.Warn("Skipping unreadable job file",
+					zap.String("path", path),
+					zap.Error(err))
+			}
+			continue
+		}
+
+		job, err := decodeJob(data)
+		if err != nil {
+			s.quarantine(path, err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// Delete removes a job
+func (s *FileJobStore) Delete(jobID string) error {
+	path, err := s.jobPath(jobID)
+	if err != nil {
+		return ErrJobNotFound
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return ErrJobNotFound
+		}
+		return fmt.Errorf("failed to delete job file: %w", err)
+	}
+
+	return nil
+}
+
+// quarantine moves a job file that cannot be decoded out of the store
+func (s *FileJobStore) quarantine(path string, cause error) {
+	err := os.Rename(path, path+corruptFileExt)
+	if os.IsNotExist(err) {
+		// Another List already moved it
+		return
+	}
+
+	fields := []zap.Field{zap.String("path", path), zap.NamedError("cause", cause)}
+	if err != nil {
+		s.logger.Error("Failed to quarantine corrupt job file", append(fields, zap.Error(err))...)
+		return
+	}
+	s.logger.Warn("Quarantined corrupt job file", fields...)
+}
+
+// jobPath returns the file path for a job, rejecting IDs that could escape the store directory
+func (s *FileJobStore) jobPath(jobID string) (string, error) {
+	if jobID == "" || strings.ContainsAny(jobID, `/\`) || strings.Contains(jobID, "..") {
+		return "", fmt.Errorf("invalid job id: %q", jobID)
+	}
+	return filepath.Join(s.dir, jobID+jobFileExt), nil
+}
+
+// readJobFile loads a job from disk
+func readJobFile(path string) (*models.Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to read job file: %w", err)
+	}
+
+	return decodeJob(data)
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"todo-agent-backend/internal/models"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// JobStore persists processing jobs.
+// Implementations return copies, so callers never share a job with the store.
+type JobStore interface {
+	Save(job *models.Job) error
+	Get(jobID string) (*models.Job, error)
+	List() ([]*models.Job, error)
+	Delete(jobID string) error
+}
+
+// MemoryJobStore keeps jobs in memory. Jobs are lost on restart.
+type MemoryJobStore struct {
+	jobs  map[string][]byte
+	mutex sync.RWMutex
+}
+
+// NewMemoryJobStore creates a new in-memory job store
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{
+		jobs: make(map[string][]byte),
+	}
+}
+
+// Save stores or replaces a job
+func (s *MemoryJobStore) Save(job *models.Job) error {
+	data, err := encodeJob(job)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.jobs[job.ID] = data
+	return nil
+}
+
+// Get retrieves a job by ID
+func (s *MemoryJobStore) Get(jobID string) (*models.Job, error) {
+	s.mutex.RLock()
+	data, exists := s.jobs[jobID]
+	s.mutex.RUnlock()
+
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+
+	return decodeJob(data)
+}
+
+// List returns all stored jobs
+func (s *MemoryJobStore) List() ([]*models.Job, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	jobs := make([]*models.Job, 0, len(s.jobs))
+	for _, data := range s.jobs {
+		job, err := decodeJob(data)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// Delete removes a job
+func (s *MemoryJobStore) Delete(jobID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.jobs[jobID]; !exists {
+		return ErrJobNotFound
+	}
+
+	delete(s.jobs, jobID)
+	return nil
+}
+
+// encodeJob serializes a job for storage
+func encodeJob(job *models.Job) ([]byte, error) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job: %w", err)
+	}
+	return data, nil
+}
+
+// decodeJob deserializes a stored job
+func decodeJob(data []byte) (*models.Job, error) {
+	var job models.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+	return &job, nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testJobStore(t *testing.T, store JobStore) {
+	job := &models.Job{
+		ID:        "job-1",
+		UserID:    "user-1",
+		Type:      "text",
+		Content:   "Send report",
+		Status:    models.JobStatusPending,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+	require.NoError(t, store.Save(job))
+
+	// Stored jobs are copies
+	job.Status = models.JobStatusFailed
+	stored, err := store.Get("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusPending, stored.Status)
+	assert.Equal(t, "Send report", stored.Content)
+
+	stored.Status = models.JobStatusCompleted
+	require.NoError(t, store.Save(stored))
+
+	jobs, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, models.JobStatusCompleted, jobs[0].Status)
+
+	require.NoError(t, store.Delete("job-1"))
+	_, err = store.Get("job-1")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	assert.ErrorIs(t, store.Delete("job-1"), ErrJobNotFound)
+}
+
+func TestMemoryJobStore(t *testing.T) {
+	testJobStore(t, NewMemoryJobStore())
+}
+
+func TestFileJobStore(t *testing.T) {
+	store, err := NewFileJobStore(t.TempDir(), logger.NewLogger("error", "console"))
+	require.NoError(t, err)
+	testJobStore(t, store)
+}
+
+func TestFileJobStore_SurvivesReopen(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileJobStore(dir, logger.NewLogger("error", "console"))
+	require.NoError(t, err)
+	require.NoError(t, store.Save(&models.Job{ID: "job-1", Status: models.JobStatusPending}))
+
+	reopened, err := NewFileJobStore(dir, logger.NewLogger("error", "console"))
+	require.NoError(t, err)
+	job, err := reopened.Get("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusPending, job.Status)
+
+	_, err = reopened.Get("../job-1")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestFileJobStore_ListSkipsCorruptFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileJobStore(dir, logger.NewLogger("error", "console"))
+	require.NoError(t, err)
+	require.NoError(t, store.Save(&models.Job{ID: "job-1", Status: models.JobStatusPending}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "job-2.json"), []byte(`{"id": "job-2", "sta`), 0644))
+
+	jobs, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "job-1", jobs[0].ID)
+
+	// The corrupt file is set aside
+	assert.NoFileExists(t, filepath.Join(dir, "job-2.json"))
+	assert.FileExists(t, filepath.Join(dir, "job-2.json.corrupt"))
+}
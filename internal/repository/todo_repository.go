@@ -1,8 +1,13 @@
 package repository
 
 import (
+	"context"
+
 	"todo-agent-backend/internal/models"
+	"todo-agent-backend/pkg/retry"
 	"todo-agent-backend/pkg/supabase"
+
+	"github.com/google/uuid"
 )
 
 // TodoRepository handles todo database operations
@@ -17,17 +22,34 @@ func NewTodoRepository(client *supabase.Client) *TodoRepository {
 	}
 }
 
+// WithRetryCounter returns a copy of the repository that records every request attempt in counter
+func (tr *TodoRepository) WithRetryCounter(counter *retry.Counter) *TodoRepository {
+	return &TodoRepository{
+		client: tr.client.WithRetryCounter(counter),
+	}
+}
+
 // InsertTodo inserts a single todo
-func (tr *TodoRepository) InsertTodo(todo *models.Todo) error {
-	return tr.client.InsertTodo(todo)
+func (tr *TodoRepository) InsertTodo(ctx context.Context, todo *models.Todo) error {
+	return tr.client.InsertTodo(ctx, todo)
 }
 
 // InsertTodos inserts multiple todos
-func (tr *TodoRepository) InsertTodos(todos []models.Todo) error {
-	return tr.client.InsertTodos(todos)
+func (tr *TodoRepository) InsertTodos(ctx context.Context, todos []models.Todo) error {
+	return tr.client.InsertTodos(ctx, todos)
 }
 
 // GetTodosByUserID retrieves todos for a user
-func (tr *TodoRepository) GetTodosByUserID(userID string) ([]models.Todo, error) {
-	return tr.client.GetTodosByUserID(userID)
+func (tr *TodoRepository) GetTodosByUserID(ctx context.Context, userID string) ([]models.Todo, error) {
+	return tr.client.GetTodosByUserID(ctx, userID)
+}
+
+// GetOpenTodosByUserID retrieves the todos of a user that are not completed
+func (tr *TodoRepository) GetOpenTodosByUserID(ctx context.Context, userID string) ([]models.Todo, error) {
+	return tr.client.GetOpenTodosByUserID(ctx, userID)
+}
+
+// UpdateTodo changes the fields set in patch on an existing todo
+func (tr *TodoRepository) UpdateTodo(ctx context.Context, id uuid.UUID, patch models.TodoPatch) error {
+	return tr.client.UpdateTodo(ctx, id, patch)
 }
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/utils"
+	"todo-agent-backend/pkg/prompt"
+
+	"go.uber.org/zap"
+)
+
+// blankLines matches line breaks around empty lines, which do not change what the model extracts
+var blankLines = regexp.MustCompile(`\n{2,}`)
+
+// SetCache makes the service reuse the todos extracted from identical
+// input instead of sending it to the model again
+func (ps *ProcessingService) SetCache(cache ResultCache) {
+	ps.cache = cache
+}
+
+// extractCached returns the cached todos for key, or extracts them and
+// stores them under key. Cache failures only cost a model call.
+func (ps *ProcessingService) extractCached(ctx context.Context, extractor TodoExtractor, input *jobInput, progress *jobProgress, key string) ([]models.TodoItem, bool, error) {
+	if ps.cache == nil {
+		todos, err := ps.extractTodos(ctx, extractor, input, progress)
+		return todos, false, err
+	}
+
+	if data, ok := ps.cache.Get(key); ok {
+		var todos []models.TodoItem
+		if err := json.Unmarshal(data, &todos); err == nil {
+			return todos, true, nil
+		}
+		ps.logger.Warn("Ignoring unreadable cache entry", zap.String("cache_key", key))
+	}
+
+	todos, err := ps.extractTodos(ctx, extractor, input, progress)
+	if err != nil {
+		return nil, false, err
+	}
+
+	data, err := json.Marshal(todos)
+	if err == nil {
+		err = ps.cache.Set(key, data)
+	}
+	if err != nil {
+		ps.logger.Warn("Failed to cache extraction result",
+			zap.String("cache_key", key),
+			zap.Error(err))
+	}
+
+	return todos, false, nil
+}
+
+// cacheKey identifies an extraction by the SHA-256 of everything that
+// shapes the model's answer: the normalized input, the prompt template
+// and version, the model, and the timezone and day that relative dates
+// are resolved against
+func cacheKey(job *models.Job, input *jobInput, tmpl *prompt.Template, model string) string {
+	loc := utils.LoadLocation(job.Timezone)
+
+	hash := sha256.New()
+	for _, field := range []string{
+		tmpl.Name, tmpl.Version, model,
+		loc.String(), referenceTime(job).In(loc).Format("2006-01-02"),
+	} {
+		fmt.Fprintf(hash, "%d:%s;", len(field), field)
+	}
+
+	if input.image != nil {
+		fmt.Fprintf(hash, "image:%s;", input.mimeType)
+		hash.Write(input.image)
+	} else if len(input.pageImages) > 0 {
+		for _, page := range input.pageImages {
+			fmt.Fprintf(hash, "page:%s:%d;", page.mimeType, len(page.data))
+			hash.Write(page.data)
+		}
+	} else {
+		fmt.Fprint(hash, "text:")
+		hash.Write([]byte(normalizeText(input.text)))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// normalizeText removes differences in line endings, trailing spaces and
+// blank lines so pasted copies of the same text share a cache entry
+func normalizeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text = strings.Join(lines, "\n")
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(text, "\n"))
+}
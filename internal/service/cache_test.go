@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+	"todo-agent-backend/pkg/cache"
+	"todo-agent-backend/pkg/prompt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessJob_ReusesCachedTodos(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	todoRepo, db := newTestTodoRepository(t)
+
+	extractor := newRecordingExtractor("")
+	ps := NewProcessingService(extractor, todoRepo, jobService, log)
+	ps.SetCache(cache.NewMemory(10, time.Hour))
+
+	createdAt := time.Date(2025, 7, 15, 9, 0, 0, 0, time.UTC)
+	process := func(id, content string) *models.Job {
+		job := newTestJob(id)
+		job.Content = content
+		job.CreatedAt = createdAt
+		require.NoError(t, jobService.SubmitJob(job))
+		ps.ProcessJob(context.Background(), job)
+
+		stored, err := jobService.GetJob(id)
+		require.NoError(t, err)
+		require.Equal(t, models.JobStatusCompleted, stored.Status, stored.Error)
+		return stored
+	}
+
+	first := process("job-1", "- Kirim laporan\n- Beli kopi")
+	assert.False(t, first.Result.CacheHit)
+
+	// The same text pasted with other line endings and spacing is a hit
+	second := process("job-2", "- Kirim laporan  \r\n\r\n\r\n- Beli kopi\n")
+	assert.True(t, second.Result.CacheHit)
+	assert.Equal(t, first.Result.Todos[0].Title, second.Result.Todos[0].Title)
+	assert.Len(t, extractor.calls(), 1)
+
+	// Cached todos are still saved for the new job
+	assert.Len(t, db.todos, 4)
+
+	// Relative dates depend on the day, so another day is a miss
+	createdAt = createdAt.Add(24 * time.Hour)
+	third := process("job-3", "- Kirim laporan\n- Beli kopi")
+	assert.False(t, third.Result.CacheHit)
+	assert.Len(t, extractor.calls(), 2)
+}
+
+func TestCacheKey(t *testing.T) {
+	createdAt := time.Date(2025, 7, 15, 9, 0, 0, 0, time.UTC)
+	job := &models.Job{CreatedAt: createdAt, Timezone: "Asia/Jakarta"}
+	input := &jobInput{text: "Kirim laporan"}
+	tmpl := prompt.Default()
+
+	key := cacheKey(job, input, tmpl, "gemini-1.5-flash")
+	assert.Len(t, key, 64)
+	assert.Equal(t, key, cacheKey(job, &jobInput{text: "  Kirim laporan\r\n"}, tmpl, "gemini-1.5-flash"))
+
+	assert.NotEqual(t, key, cacheKey(job, input, tmpl, "gpt-4o-mini"))
+	assert.NotEqual(t, key, cacheKey(job, &jobInput{text: "Beli kopi"}, tmpl, "gemini-1.5-flash"))
+
+	other, err := prompt.Parse(prompt.Meta{Name: tmpl.Name, Version: "next"}, `{{define "text"}}{{end}}{{define "image"}}{{end}}{{define "repair"}}{{end}}`)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, cacheKey(job, input, other, "gemini-1.5-flash"))
+
+	image := &jobInput{image: []byte("png"), mimeType: "image/png"}
+	assert.NotEqual(t, cacheKey(job, image, tmpl, "gemini-1.5-flash"), cacheKey(job, &jobInput{image: []byte("jpg"), mimeType: "image/png"}, tmpl, "gemini-1.5-flash"))
+}
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"todo-agent-backend/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// segmentBreak matches the boundaries long inputs are preferably split at:
+// blank lines between paragraphs and the page headers of PDF text
+var segmentBreak = regexp.MustCompile(`\n\s*\n|\n(?:--- Page \d+ ---\n)`)
+
+// SetChunking configures how long inputs are split. Inputs longer than
+// size characters are split into chunks that repeat up to overlap
+// characters of the previous chunk, and at most concurrency chunks are
+// sent to the model at once. Inputs are not split until this is called.
+func (ps *ProcessingService) SetChunking(size, overlap, concurrency int) {
+	ps.chunkSize = size
+	ps.chunkOverlap = overlap
+	ps.chunkConcurrency = concurrency
+}
+
+// extractChunked extracts todos from each chunk of the input text
+// concurrently, then merges the results in input order. The first failing
+// chunk cancels the others and fails the extraction.
+func (ps *ProcessingService) extractChunked(ctx context.Context, extractor TodoExtractor, text string, progress *jobProgress) ([]models.TodoItem, error) {
+	chunks := splitText(text, ps.chunkSize, ps.chunkOverlap)
+	progress.setChunks(len(chunks))
+	if len(chunks) == 1 {
+		todos, err := extractor.ExtractTodos(ctx, chunks[0])
+		if err == nil {
+			progress.chunkDone()
+		}
+		return todos, err
+	}
+
+	results, err := ps.extractParts(ctx, len(chunks), progress, func(ctx context.Context, i int) ([]models.TodoItem, error) {
+		return extractor.ExtractTodos(ctx, chunks[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeTodos(results...), nil
+}
+
+// extractParts runs extract for parts 0 to count-1 with at most the
+// chunk concurrency running at once, and returns their todos in part
+// order. The first failing part cancels the others and fails the
+// extraction.
+func (ps *ProcessingService) extractParts(ctx context.Context, count int, progress *jobProgress, extract func(ctx context.Context, i int) ([]models.TodoItem, error)) ([][]models.TodoItem, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := ps.chunkConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+
+	results := make([][]models.TodoItem, count)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			todos, err := extract(ctx, i)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+
+			results[i] = todos
+			progress.chunkDone()
+			ps.logger.Debug("Chunk extracted",
+				zap.Int("chunk", i+1),
+				zap.Int("chunks", count),
+				zap.Int("todos_count", len(todos)))
+		}(i)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// extractPageImages extracts todos from each scanned page image with the
+// vision model and merges the results in page order
+func (ps *ProcessingService) extractPageImages(ctx context.Context, extractor TodoExtractor, images []pageImage, progress *jobProgress) ([]models.TodoItem, error) {
+	progress.setChunks(len(images))
+
+	results, err := ps.extractParts(ctx, len(images), progress, func(ctx context.Context, i int) ([]models.TodoItem, error) {
+		return extractor.ExtractTodosFromImage(ctx, images[i].data, images[i].mimeType)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeTodos(results...), nil
+}
+
+// splitText splits text into chunks of at most size characters. Chunks
+// end at paragraph or page boundaries where possible, falling back to
+// line breaks and spaces, and start with up to overlap characters from
+// the end of the previous chunk, cut at a word boundary, so items on a
+// boundary are seen whole.
+func splitText(text string, size, overlap int) []string {
+	if size <= 0 || utf8.RuneCountInString(text) <= size {
+		return []string{text}
+	}
+	if overlap < 0 || overlap >= size/2 {
+		overlap = size / 4
+	}
+
+	// Pieces of long paragraphs leave room for the overlap
+	var segments []string
+	for _, segment := range splitKeep(text, segmentBreak) {
+		segments = append(segments, splitLong(segment, size-overlap)...)
+	}
+
+	var chunks []string
+	var current []string
+	length := 0
+
+	for _, segment := range segments {
+		n := utf8.RuneCountInString(segment)
+		if length+n > size && len(current) > 0 {
+			chunk := strings.Join(current, "")
+			chunks = append(chunks, chunk)
+
+			// Repeat the end of the chunk at the start of the next one
+			current, length = nil, 0
+			if carried := overlapTail(chunk, min(overlap, size-n)); carried != "" {
+				current = []string{carried}
+				length = utf8.RuneCountInString(carried)
+			}
+		}
+
+		current = append(current, segment)
+		length += n
+	}
+
+	if len(current) > 0 {
+		chunks = append(chunks, strings.Join(current, ""))
+	}
+
+	return chunks
+}
+
+// overlapTail returns at most n characters from the end of text. It
+// starts at a line break when there is one, so list items are repeated
+// whole, and otherwise at the beginning of a word.
+func overlapTail(text string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+
+	skip := utf8.RuneCountInString(text) - n
+	if skip <= 0 {
+		return text
+	}
+
+	start := 0
+	for i := 0; i < skip; i++ {
+		_, width := utf8.DecodeRuneInString(text[start:])
+		start += width
+	}
+
+	// Drop the partial line or word the cut landed in
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	switch line := strings.IndexByte(text[start:], '\n'); {
+	case before == '\n':
+	case line >= 0:
+		start += line
+	case !unicode.IsSpace(before):
+		space := strings.IndexFunc(text[start:], unicode.IsSpace)
+		if space < 0 {
+			return ""
+		}
+		start += space
+	}
+
+	return strings.TrimLeftFunc(text[start:], unicode.IsSpace)
+}
+
+// splitKeep splits text after every match of sep, keeping the separators
+// so joining the parts gives back the text
+func splitKeep(text string, sep *regexp.Regexp) []string {
+	var parts []string
+	start := 0
+	for _, m := range sep.FindAllStringIndex(text, -1) {
+		// Page headers belong to the page they introduce
+		end := m[1]
+		if header := strings.Index(text[m[0]:m[1]], "--- Page"); header >= 0 {
+			end = m[0] + header
+		}
+		if end > start {
+			parts = append(parts, text[start:end])
+			start = end
+		}
+	}
+	if start < len(text) {
+		parts = append(parts, text[start:])
+	}
+	return parts
+}
+
+// splitLong breaks a segment longer than size at line breaks, then at
+// spaces, and as a last resort between characters
+func splitLong(segment string, size int) []string {
+	if utf8.RuneCountInString(segment) <= size {
+		return []string{segment}
+	}
+
+	var parts []string
+	for len(segment) > 0 {
+		if utf8.RuneCountInString(segment) <= size {
+			parts = append(parts, segment)
+			break
+		}
+
+		// Byte offset just past the first size characters
+		limit := 0
+		for i := 0; i < size; i++ {
+			_, n := utf8.DecodeRuneInString(segment[limit:])
+			limit += n
+		}
+
+		cut := strings.LastIndexByte(segment[:limit], '\n') + 1
+		if cut <= 0 {
+			cut = strings.LastIndexByte(segment[:limit], ' ') + 1
+		}
+		if cut <= 0 {
+			cut = limit
+		}
+
+		parts = append(parts, segment[:cut])
+		segment = segment[cut:]
+	}
+	return parts
+}
+
+// mergeTodos joins the todo lists of consecutive chunks. Overlapping
+// chunks often report the same todo twice, so an item with the same title
+// as an item of an earlier list and a due date on the same day, or none,
+// is merged into the first one, which keeps its position. Items of one
+// list are never merged with each other.
+func mergeTodos(lists ...[]models.TodoItem) []models.TodoItem {
+	var merged []models.TodoItem
+	index := make(map[string][]int)
+	var lastList []int // the last list each merged item has an item of
+
+	for l, list := range lists {
+		for _, item := range list {
+			key := titleKey(item.Title)
+
+			match := -1
+			for _, i := range index[key] {
+				if lastList[i] < l && sameDueDay(merged[i].DueDate, item.DueDate) {
+					match = i
+					break
+				}
+			}
+			if match >= 0 {
+				merged[match] = mergeTodo(merged[match], item)
+				lastList[match] = l
+				continue
+			}
+
+			index[key] = append(index[key], len(merged))
+			merged = append(merged, item)
+			lastList = append(lastList, l)
+		}
+	}
+
+	return merged
+}
+
+// sameDueDay reports whether two YYYY-MM-DD due dates are the same day or either is missing
+func sameDueDay(a, b *string) bool {
+	return a == nil || b == nil || *a == *b
+}
+
+// mergeTodo fills the fields a todo is missing from a duplicate of it
+func mergeTodo(todo, duplicate models.TodoItem) models.TodoItem {
+	if todo.Description == "" {
+		todo.Description = duplicate.Description
+	}
+	if todo.DueDate == nil {
+		todo.DueDate = duplicate.DueDate
+	}
+	if todo.DueTime == nil {
+		todo.DueTime = duplicate.DueTime
+	}
+	if todo.Priority == "" {
+		todo.Priority = duplicate.Priority
+	}
+	if todo.EstimatedMinutes == nil {
+		todo.EstimatedMinutes = duplicate.EstimatedMinutes
+	}
+	if todo.Assignee == "" {
+		todo.Assignee = duplicate.Assignee
+	}
+	if todo.Source == nil {
+		todo.Source = duplicate.Source
+	}
+
+	for _, tag := range duplicate.Tags {
+		if !contains(todo.Tags, tag) {
+			todo.Tags = append(todo.Tags, tag)
+		}
+	}
+
+	todo.Subtasks = mergeTodos(todo.Subtasks, duplicate.Subtasks)
+
+	return todo
+}
+
+// titleKey normalizes a title for duplicate detection by ignoring case,
+// punctuation and spacing
+func titleKey(title string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			space = true
+		}
+	}
+	if b.Len() == 0 {
+		return strings.TrimSpace(title)
+	}
+	return b.String()
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
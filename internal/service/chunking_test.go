@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+	"todo-agent-backend/pkg/prompt"
+	"todo-agent-backend/pkg/retry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitText_ShortTextIsOneChunk(t *testing.T) {
+	assert.Equal(t, []string{"- Beli kopi"}, splitText("- Beli kopi", 100, 10))
+	assert.Equal(t, []string{"- Beli kopi"}, splitText("- Beli kopi", 0, 0))
+}
+
+func TestSplitText_SplitsAtParagraphsWithOverlap(t *testing.T) {
+	paragraphs := []string{
+		strings.Repeat("a", 40),
+		strings.Repeat("b", 40),
+		strings.Repeat("c", 40),
+		strings.Repeat("d", 40),
+	}
+	text := strings.Join(paragraphs, "\n\n")
+
+	chunks := splitText(text, 90, 42)
+	require.Len(t, chunks, 3)
+
+	// Every chunk ends on a paragraph boundary and repeats the last
+	// paragraph of the one before it
+	assert.Equal(t, paragraphs[0]+"\n\n"+paragraphs[1]+"\n\n", chunks[0])
+	assert.Equal(t, paragraphs[1]+"\n\n"+paragraphs[2]+"\n\n", chunks[1])
+	assert.Equal(t, paragraphs[2]+"\n\n"+paragraphs[3], chunks[2])
+}
+
+func TestSplitText_KeepsPageHeaderWithItsPage(t *testing.T) {
+	text := "--- Page 1 ---\n" + strings.Repeat("a", 50) +
+		"\n\n--- Page 2 ---\n" + strings.Repeat("b", 50)
+
+	chunks := splitText(text, 80, 0)
+	require.Len(t, chunks, 2)
+	assert.True(t, strings.HasPrefix(chunks[0], "--- Page 1 ---\n"))
+	assert.True(t, strings.HasPrefix(chunks[1], "--- Page 2 ---\n"))
+}
+
+func TestSplitText_BreaksLongParagraphs(t *testing.T) {
+	words := strings.TrimSpace(strings.Repeat("tugas ", 100))
+	chunks := splitText(words, 50, 0)
+
+	require.Greater(t, len(chunks), 1)
+	assert.Equal(t, words, strings.Join(chunks, ""))
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, utf8.RuneCountInString(chunk), 50)
+		assert.False(t, strings.HasPrefix(chunk, "ugas"), "split inside a word: %q", chunk)
+	}
+
+	// Without spaces the text is split between characters
+	unbroken := strings.Repeat("é", 120)
+	chunks = splitText(unbroken, 50, 0)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, unbroken, strings.Join(chunks, ""))
+}
+
+func TestSplitText_OverlapsLongParagraphs(t *testing.T) {
+	var words []string
+	for i := 1; i <= 60; i++ {
+		words = append(words, fmt.Sprintf("w%03d", i))
+	}
+	paragraph := strings.Join(words, " ")
+
+	chunks := splitText(paragraph, 50, 20)
+	require.Greater(t, len(chunks), 1)
+	assert.True(t, strings.HasSuffix(chunks[len(chunks)-1], "w060"))
+
+	for i, chunk := range chunks {
+		assert.LessOrEqual(t, utf8.RuneCountInString(chunk), 50)
+		if i == 0 {
+			continue
+		}
+
+		// Each chunk starts with whole words from the end of the one before
+		first := strings.Fields(chunk)[0]
+		assert.Regexp(t, `^w\d{3}$`, first)
+		prev := chunks[i-1]
+		assert.Contains(t, prev[len(prev)-20:], first)
+	}
+}
+
+func TestOverlapTail(t *testing.T) {
+	assert.Equal(t, "gamma delta", overlapTail("alpha beta gamma delta", 13))
+	assert.Equal(t, "- Tugas 02\n", overlapTail("- Tugas 01\n- Tugas 02\n", 14))
+	assert.Equal(t, "", overlapTail("alphabetagamma", 5))
+	assert.Equal(t, "", overlapTail("alpha beta", 0))
+}
+
+func TestMergeTodos_DeduplicatesAcrossChunks(t *testing.T) {
+	due := "2025-07-18"
+	minutes := 30
+
+	merged := mergeTodos(
+		[]models.TodoItem{
+			{Title: "Kirim laporan", Tags: []string{"kantor"}},
+			{Title: "Beli kopi"},
+		},
+		[]models.TodoItem{
+			{
+				Title:            "kirim  laporan!",
+				Description:      "Ke tim keuangan",
+				DueDate:          &due,
+				EstimatedMinutes: &minutes,
+				Tags:             []string{"kantor", "keuangan"},
+				Subtasks:         []models.TodoItem{{Title: "Cek angka"}},
+			},
+			{Title: "Telepon klien"},
+		},
+	)
+
+	require.Len(t, merged, 3)
+	assert.Equal(t, "Kirim laporan", merged[0].Title)
+	assert.Equal(t, "Ke tim keuangan", merged[0].Description)
+	assert.Equal(t, &due, merged[0].DueDate)
+	assert.Equal(t, &minutes, merged[0].EstimatedMinutes)
+	assert.Equal(t, []string{"kantor", "keuangan"}, merged[0].Tags)
+	require.Len(t, merged[0].Subtasks, 1)
+	assert.Equal(t, "Beli kopi", merged[1].Title)
+	assert.Equal(t, "Telepon klien", merged[2].Title)
+}
+
+func TestMergeTodos_KeepsDistinctTodos(t *testing.T) {
+	monday, tuesday := "2025-07-14", "2025-07-15"
+
+	// The overlap repeats Tuesday's call, and the first chunk's todos
+	// share a title but not a day
+	merged := mergeTodos(
+		[]models.TodoItem{
+			{Title: "Call Budi", DueDate: &monday},
+			{Title: "Call Budi", DueDate: &tuesday},
+		},
+		[]models.TodoItem{
+			{Title: "Call Budi", DueDate: &tuesday, Description: "About the invoice"},
+			{Title: "Call Budi"},
+		},
+	)
+
+	require.Len(t, merged, 2)
+	assert.Equal(t, &monday, merged[0].DueDate)
+	assert.Equal(t, &tuesday, merged[1].DueDate)
+	assert.Equal(t, "About the invoice", merged[1].Description)
+
+	// A single list is returned as is
+	assert.Len(t, mergeTodos([]models.TodoItem{{Title: "Call Budi"}, {Title: "Call Budi"}}), 2)
+}
+
+func TestProcessJob_ExtractsLongTextInChunks(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	todoRepo, db := newTestTodoRepository(t)
+
+	extractor := newRecordingExtractor("")
+	ps := NewProcessingService(extractor, todoRepo, jobService, log)
+	ps.SetChunking(60, 25, 2)
+
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, fmt.Sprintf("- Tugas nomor %02d", i))
+	}
+
+	job := newTestJob("job-1")
+	job.Content = strings.Join(lines, "\n\n")
+	require.NoError(t, jobService.SubmitJob(job))
+
+	ps.ProcessJob(context.Background(), job)
+
+	stored, err := jobService.GetJob("job-1")
+	require.NoError(t, err)
+	require.Equal(t, models.JobStatusCompleted, stored.Status, stored.Error)
+
+	// Overlapping chunks repeat items, but each todo is kept once and in order
+	require.Len(t, stored.Result.Todos, 10)
+	for i, todo := range stored.Result.Todos {
+		assert.Equal(t, fmt.Sprintf("Tugas nomor %02d", i+1), todo.Title)
+		require.NotNil(t, todo.Source.Start)
+		assert.Equal(t, strings.Index(job.Content, todo.Title), *todo.Source.Start)
+	}
+	assert.Len(t, db.todos, 10)
+
+	chunks := extractor.calls()
+	assert.Greater(t, len(chunks), 1)
+	assert.Equal(t, len(chunks), stored.Progress.ChunksTotal)
+	assert.Equal(t, len(chunks), stored.Progress.ChunksDone)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, utf8.RuneCountInString(chunk), 60)
+	}
+}
+
+func TestProcessJob_ChunkFailureFailsJob(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	todoRepo, db := newTestTodoRepository(t)
+
+	extractor := newRecordingExtractor("Tugas 3")
+	ps := NewProcessingService(extractor, todoRepo, jobService, log)
+	ps.SetChunking(20, 0, 1)
+
+	job := newTestJob("job-1")
+	job.Content = "- Tugas 1\n\n- Tugas 2\n\n- Tugas 3\n\n- Tugas 4"
+	require.NoError(t, jobService.SubmitJob(job))
+
+	ps.ProcessJob(context.Background(), job)
+
+	stored, err := jobService.GetJob("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusFailed, stored.Status)
+	assert.Contains(t, stored.Error, "model unavailable")
+	assert.Empty(t, db.todos)
+	assert.Less(t, stored.Progress.ChunksDone, stored.Progress.ChunksTotal)
+}
+
+// recordingExtractor wraps FakeExtractor, keeping the text of every call
+// and failing calls whose text contains failOn
+type recordingExtractor struct {
+	*FakeExtractor
+	failOn string
+
+	mu     sync.Mutex
+	chunks []string
+}
+
+func newRecordingExtractor(failOn string) *recordingExtractor {
+	return &recordingExtractor{FakeExtractor: &FakeExtractor{}, failOn: failOn}
+}
+
+func (r *recordingExtractor) ExtractTodos(ctx context.Context, text string) ([]models.TodoItem, error) {
+	r.mu.Lock()
+	r.chunks = append(r.chunks, text)
+	r.mu.Unlock()
+
+	if r.failOn != "" && strings.Contains(text, r.failOn) {
+		return nil, fmt.Errorf("model unavailable")
+	}
+	return r.FakeExtractor.ExtractTodos(ctx, text)
+}
+
+func (r *recordingExtractor) WithRetryCounter(*retry.Counter) TodoExtractor  { return r }
+func (r *recordingExtractor) WithRepairCounter(*retry.Counter) TodoExtractor { return r }
+func (r *recordingExtractor) WithPrompt(prompt.Prompt) TodoExtractor         { return r }
+
+func (r *recordingExtractor) calls() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.chunks...)
+}
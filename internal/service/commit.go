@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+	"todo-agent-backend/pkg/prompt"
+	"todo-agent-backend/pkg/retry"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrNoPreview        = errors.New("job has no preview to commit")
+	ErrCommitInProgress = errors.New("job is already being committed")
+	ErrInvalidTodos     = errors.New("invalid todos")
+)
+
+// CommitJob saves todos, usually the edited todos of a job that was
+// processed in preview mode, and records them as the job's result. The
+// todos are checked like model replies and deduplicated with the job's
+// dedup mode. A preview can be committed once. A commit that failed after
+// it started saving is finished with its own todos when retried, and the
+// todos passed to the retry are ignored.
+func (ps *ProcessingService) CommitJob(ctx context.Context, jobID string, todos []models.TodoItem) (*models.Job, error) {
+	// Concurrent commits of one job would insert its todos twice
+	if _, busy := ps.committing.LoadOrStore(jobID, struct{}{}); busy {
+		return nil, ErrCommitInProgress
+	}
+	defer ps.committing.Delete(jobID)
+
+	job, err := ps.jobService.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.JobStatusCompleted || job.Result == nil || !job.Result.Preview {
+		return nil, ErrNoPreview
+	}
+
+	attempts := &retry.Counter{}
+	todoRepo := ps.todoRepo.WithRetryCounter(attempts)
+
+	result := *job.Result
+	if result.CommitID == nil {
+		if err := ps.prepareCommit(ctx, todoRepo, job, &result, todos); err != nil {
+			return nil, err
+		}
+	}
+
+	// Rows saved by an earlier attempt keep their IDs and are skipped
+	if err := ps.saveTodosToDatabase(ctx, todoRepo, job, &result); err != nil {
+		return nil, fmt.Errorf("failed to save todos: %w", err)
+	}
+
+	committedAt := time.Now()
+	result.Preview = false
+	result.CommittedAt = &committedAt
+
+	if err := ps.jobService.UpdateResult(jobID, &result); err != nil {
+		return nil, err
+	}
+
+	ps.logger.Info("Job preview committed",
+		zap.String("job_id", jobID),
+		zap.Int("todos_count", len(result.Todos)),
+		zap.Int("duplicates_count", len(result.Duplicates)),
+		zap.Int("request_attempts", attempts.Attempts()))
+
+	return ps.jobService.GetJob(jobID)
+}
+
+// prepareCommit sets the checked and deduplicated todos of a commit on
+// result and records them on the job with a new CommitID before anything
+// is inserted
+func (ps *ProcessingService) prepareCommit(ctx context.Context, todoRepo *repository.TodoRepository, job *models.Job, result *models.ProcessingResult, todos []models.TodoItem) error {
+	todos, err := prompt.ValidateTodos(todos)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTodos, err)
+	}
+
+	result.Todos = todos
+	result.Duplicates = nil
+
+	if err := ps.dedupTodos(ctx, todoRepo, job, result); err != nil {
+		return fmt.Errorf("failed to check for duplicate todos: %w", err)
+	}
+
+	commitID := uuid.New()
+	result.CommitID = &commitID
+
+	return ps.jobService.UpdateResult(job.ID, result)
+}
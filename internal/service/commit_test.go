@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitJob_SavesEditedPreview(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	todoRepo, db := newTestTodoRepository(t)
+
+	ps := NewProcessingService(&FakeExtractor{}, todoRepo, jobService, log)
+
+	job := newTestJob("job-1")
+	job.Content = "- Kirim laporan 2025-07-18\n- Beli kopi"
+	job.Mode = models.JobModePreview
+	require.NoError(t, jobService.SubmitJob(job))
+
+	ps.ProcessJob(context.Background(), job)
+
+	// Nothing is saved until the preview is committed
+	stored, err := jobService.GetJob("job-1")
+	require.NoError(t, err)
+	require.Equal(t, models.JobStatusCompleted, stored.Status, stored.Error)
+	assert.True(t, stored.Result.Preview)
+	assert.Len(t, stored.Result.Todos, 2)
+	assert.Empty(t, db.todos)
+
+	// Invalid edits are rejected and the preview stays
+	_, err = ps.CommitJob(context.Background(), "job-1", []models.TodoItem{{Title: " "}})
+	assert.ErrorIs(t, err, ErrInvalidTodos)
+	assert.Empty(t, db.todos)
+
+	edited := []models.TodoItem{
+		{Title: "Kirim laporan Q3", Priority: "High"},
+		{Title: "Beli kopi", Subtasks: []models.TodoItem{{Title: "Cari diskon"}}},
+	}
+	committed, err := ps.CommitJob(context.Background(), "job-1", edited)
+	require.NoError(t, err)
+	assert.False(t, committed.Result.Preview)
+	assert.NotNil(t, committed.Result.CommittedAt)
+	require.Len(t, committed.Result.Todos, 2)
+	assert.Equal(t, "high", committed.Result.Todos[0].Priority)
+
+	require.Len(t, db.todos, 3)
+	assert.Equal(t, "Kirim laporan Q3", db.todos[0].Title)
+
+	// A preview can only be committed once
+	_, err = ps.CommitJob(context.Background(), "job-1", edited)
+	assert.ErrorIs(t, err, ErrNoPreview)
+	assert.Len(t, db.todos, 3)
+
+	_, err = ps.CommitJob(context.Background(), "missing", edited)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestCommitJob_RequiresPreview(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	todoRepo, db := newTestTodoRepository(t)
+
+	ps := NewProcessingService(&FakeExtractor{}, todoRepo, jobService, log)
+
+	job := newTestJob("job-1")
+	require.NoError(t, jobService.SubmitJob(job))
+	ps.ProcessJob(context.Background(), job)
+	require.Len(t, db.todos, 1)
+
+	_, err := ps.CommitJob(context.Background(), "job-1", []models.TodoItem{{Title: "Send report"}})
+	assert.ErrorIs(t, err, ErrNoPreview)
+	assert.Len(t, db.todos, 1)
+}
+
+// failingResultStore fails to save the first committed result
+type failingResultStore struct {
+	repository.JobStore
+	failed bool
+}
+
+func (s *failingResultStore) Save(job *models.Job) error {
+	if !s.failed && job.Result != nil && job.Result.CommittedAt != nil {
+		s.failed = true
+		return errors.New("disk full")
+	}
+	return s.JobStore.Save(job)
+}
+
+func TestCommitJob_RetryAfterFailedUpdate(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(&failingResultStore{JobStore: repository.NewMemoryJobStore()}, log)
+	todoRepo, db := newTestTodoRepository(t)
+
+	ps := NewProcessingService(&FakeExtractor{}, todoRepo, jobService, log)
+
+	job := newTestJob("job-1")
+	job.Content = "- Kirim laporan\n  - Cek angka\n- Beli kopi"
+	job.Mode = models.JobModePreview
+	require.NoError(t, jobService.SubmitJob(job))
+	ps.ProcessJob(context.Background(), job)
+
+	stored, err := jobService.GetJob("job-1")
+	require.NoError(t, err)
+	edited := stored.Result.Todos
+
+	// The todos are saved but the commit is not recorded
+	_, err = ps.CommitJob(context.Background(), "job-1", edited)
+	require.Error(t, err)
+	require.Len(t, db.todos, 3)
+	saved := append([]models.Todo{}, db.todos...)
+
+	// The retry saves the same rows, which are ignored
+	committed, err := ps.CommitJob(context.Background(), "job-1", edited)
+	require.NoError(t, err)
+	assert.NotNil(t, committed.Result.CommittedAt)
+	require.Len(t, db.todos, 3)
+	for i := range saved {
+		assert.Equal(t, saved[i].ID, db.todos[i].ID)
+	}
+	assert.Equal(t, saved[1].ParentID, db.todos[1].ParentID)
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+	"todo-agent-backend/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// titleSimilarity is the share of words two titles must have in common
+// for the todos to count as duplicates
+const titleSimilarity = 0.8
+
+// dedupTodos compares the items of a result with the user's open todos
+// and handles the ones that repeat an existing todo according to the
+// job's dedup mode. Matches are reported in result.Duplicates.
+func (ps *ProcessingService) dedupTodos(ctx context.Context, todoRepo *repository.TodoRepository, job *models.Job, result *models.ProcessingResult) error {
+	if job.DedupMode == "" || job.DedupMode == models.DedupModeOff || len(result.Todos) == 0 {
+		return nil
+	}
+
+	existing, err := todoRepo.GetOpenTodosByUserID(ctx, job.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load existing todos: %w", err)
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	// Top-level items are compared with top-level todos, and subtasks
+	// merged into a todo with the subtasks it already has
+	var roots []models.Todo
+	children := make(map[uuid.UUID][]models.Todo)
+	for _, todo := range existing {
+		if todo.ParentID != nil {
+			children[*todo.ParentID] = append(children[*todo.ParentID], todo)
+		} else {
+			roots = append(roots, todo)
+		}
+	}
+
+	loc := utils.LoadLocation(job.Timezone)
+	kept := make([]models.TodoItem, 0, len(result.Todos))
+
+	for _, item := range result.Todos {
+		match := findDuplicate(item, roots, loc)
+		if match == nil {
+			kept = append(kept, item)
+			continue
+		}
+
+		duplicate := models.DuplicateTodo{Title: item.Title, DuplicateOf: match.ID}
+
+		switch job.DedupMode {
+		case models.DedupModeSkip:
+			duplicate.Action = models.DuplicateSkipped
+
+		case models.DedupModeFlag:
+			duplicate.Action = models.DuplicateFlagged
+			id := match.ID
+			item.DuplicateOf = &id
+			kept = append(kept, item)
+
+		case models.DedupModeMerge:
+			duplicate.Action = models.DuplicateMerged
+			if patch, changed := mergePatch(*match, item, loc); changed {
+				if err := todoRepo.UpdateTodo(ctx, match.ID, patch); err != nil {
+					return fmt.Errorf("failed to merge into todo %s: %w", match.ID, err)
+				}
+				// Later items repeating the same todo merge into the patched state
+				applyPatch(match, patch)
+			}
+
+			// New steps go under the existing todo
+			for _, subtask := range item.Subtasks {
+				if findDuplicate(subtask, children[match.ID], loc) != nil {
+					continue
+				}
+				id := match.ID
+				subtask.ParentID = &id
+				kept = append(kept, subtask)
+				children[id] = append(children[id], models.Todo{
+					Title:   subtask.Title,
+					DueDate: utils.ParseDueDate(subtask.DueDate, subtask.DueTime, loc),
+				})
+			}
+
+		default:
+			return fmt.Errorf("unsupported dedup mode: %s", job.DedupMode)
+		}
+
+		result.Duplicates = append(result.Duplicates, duplicate)
+	}
+
+	result.Todos = kept
+	return nil
+}
+
+// findDuplicate returns the first todo with a similar title and a due
+// date on the same day as item, or nil. A missing due date on either
+// side does not prevent a match.
+func findDuplicate(item models.TodoItem, todos []models.Todo, loc *time.Location) *models.Todo {
+	due := utils.ParseDueDate(item.DueDate, item.DueTime, loc)
+	for i := range todos {
+		if similarTitles(item.Title, todos[i].Title) && sameDay(due, todos[i].DueDate, loc) {
+			return &todos[i]
+		}
+	}
+	return nil
+}
+
+// similarTitles compares titles after normalization, by the share of
+// words they have in common
+func similarTitles(a, b string) bool {
+	a, b = titleKey(a), titleKey(b)
+	if a == b {
+		return true
+	}
+
+	wordsA, wordsB := wordSet(a), wordSet(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return false
+	}
+
+	common := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			common++
+		}
+	}
+	union := len(wordsA) + len(wordsB) - common
+
+	return float64(common)/float64(union) >= titleSimilarity
+}
+
+func wordSet(title string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(title) {
+		words[word] = true
+	}
+	return words
+}
+
+// sameDay reports whether two due dates fall on the same day in loc, or either is missing
+func sameDay(a, b *time.Time, loc *time.Location) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	return a.In(loc).Format("2006-01-02") == b.In(loc).Format("2006-01-02")
+}
+
+// mergePatch fills the fields an existing todo is missing from a new
+// item that repeats it, and adds the item's tags
+func mergePatch(todo models.Todo, item models.TodoItem, loc *time.Location) (models.TodoPatch, bool) {
+	var patch models.TodoPatch
+	changed := false
+
+	if (todo.Description == nil || *todo.Description == "") && item.Description != "" {
+		description := item.Description
+		patch.Description = &description
+		changed = true
+	}
+	if todo.DueDate == nil {
+		if due := utils.ParseDueDate(item.DueDate, item.DueTime, loc); due != nil {
+			patch.DueDate = due
+			changed = true
+		}
+	}
+	if todo.Priority == nil && item.Priority != "" {
+		priority := item.Priority
+		patch.Priority = &priority
+		changed = true
+	}
+	if todo.EstimatedMinutes == nil && item.EstimatedMinutes != nil {
+		patch.EstimatedMinutes = item.EstimatedMinutes
+		changed = true
+	}
+	if (todo.Assignee == nil || *todo.Assignee == "") && item.Assignee != "" {
+		assignee := item.Assignee
+		patch.Assignee = &assignee
+		changed = true
+	}
+
+	tags := append([]string{}, todo.Tags...)
+	for _, tag := range item.Tags {
+		if !contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > len(todo.Tags) {
+		patch.Tags = tags
+		changed = true
+	}
+
+	return patch, changed
+}
+
+// applyPatch sets the fields of patch on todo
+func applyPatch(todo *models.Todo, patch models.TodoPatch) {
+	if patch.Description != nil {
+		todo.Description = patch.Description
+	}
+	if patch.DueDate != nil {
+		todo.DueDate = patch.DueDate
+	}
+	if patch.Priority != nil {
+		todo.Priority = patch.Priority
+	}
+	if patch.Tags != nil {
+		todo.Tags = patch.Tags
+	}
+	if patch.EstimatedMinutes != nil {
+		todo.EstimatedMinutes = patch.EstimatedMinutes
+	}
+	if patch.Assignee != nil {
+		todo.Assignee = patch.Assignee
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimilarTitles(t *testing.T) {
+	assert.True(t, similarTitles("Kirim laporan", "kirim  laporan!"))
+	assert.True(t, similarTitles("Kirim laporan keuangan Q3 ke tim", "Kirim laporan keuangan Q3 ke tim pusat"))
+	assert.False(t, similarTitles("Kirim laporan", "Kirim undangan"))
+	assert.False(t, similarTitles("Beli kopi", "Beli kopi dan gula"))
+}
+
+func TestFindDuplicate_ComparesDueDates(t *testing.T) {
+	loc := time.UTC
+	july18 := time.Date(2025, 7, 18, 0, 0, 0, 0, loc)
+	existing := []models.Todo{{ID: uuid.New(), Title: "Kirim laporan", DueDate: &july18}}
+
+	sameDay := "2025-07-18"
+	otherDay := "2025-07-25"
+
+	assert.NotNil(t, findDuplicate(models.TodoItem{Title: "Kirim laporan", DueDate: &sameDay}, existing, loc))
+	assert.NotNil(t, findDuplicate(models.TodoItem{Title: "Kirim laporan"}, existing, loc))
+	assert.Nil(t, findDuplicate(models.TodoItem{Title: "Kirim laporan", DueDate: &otherDay}, existing, loc))
+}
+
+// newDedupTest processes content with the given dedup mode while the user
+// already has an open "Kirim laporan 2025-07-18" todo with a "Cek angka" step.
+// The fake extractor keeps dates in titles and also reads them as the due date.
+func newDedupTest(t *testing.T, mode string, content string) (*models.Job, *fakeSupabase, uuid.UUID) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	todoRepo, db := newTestTodoRepository(t)
+
+	existingID := uuid.New()
+	childID := uuid.New()
+	db.existing = []models.Todo{
+		{ID: existingID, UserID: "test-user", Title: "Kirim laporan 2025-07-18", Tags: []string{"kantor"}},
+		{ID: childID, UserID: "test-user", Title: "Cek angka", ParentID: &existingID},
+	}
+
+	ps := NewProcessingService(&FakeExtractor{}, todoRepo, jobService, log)
+
+	job := newTestJob("job-1")
+	job.Content = content
+	job.DedupMode = mode
+	require.NoError(t, jobService.SubmitJob(job))
+
+	ps.ProcessJob(context.Background(), job)
+
+	stored, err := jobService.GetJob("job-1")
+	require.NoError(t, err)
+	require.Equal(t, models.JobStatusCompleted, stored.Status, stored.Error)
+
+	return stored, db, existingID
+}
+
+func TestProcessJob_DedupSkip(t *testing.T) {
+	job, db, existingID := newDedupTest(t, models.DedupModeSkip, "- Kirim laporan 2025-07-18\n- Beli kopi")
+
+	require.Len(t, db.todos, 1)
+	assert.Equal(t, "Beli kopi", db.todos[0].Title)
+
+	require.Len(t, job.Result.Todos, 1)
+	assert.Equal(t, []models.DuplicateTodo{
+		{Title: "Kirim laporan 2025-07-18", DuplicateOf: existingID, Action: models.DuplicateSkipped},
+	}, job.Result.Duplicates)
+}
+
+func TestProcessJob_DedupFlag(t *testing.T) {
+	job, db, existingID := newDedupTest(t, models.DedupModeFlag, "- Kirim laporan 2025-07-18\n- Beli kopi")
+
+	require.Len(t, db.todos, 2)
+	require.NotNil(t, db.todos[0].DuplicateOf)
+	assert.Equal(t, existingID, *db.todos[0].DuplicateOf)
+	assert.Nil(t, db.todos[1].DuplicateOf)
+
+	require.Len(t, job.Result.Duplicates, 1)
+	assert.Equal(t, models.DuplicateFlagged, job.Result.Duplicates[0].Action)
+}
+
+func TestProcessJob_DedupMerge(t *testing.T) {
+	content := "- Kirim laporan 2025-07-18\n  - Cek angka\n  - Tanda tangan direktur\n- Beli kopi"
+	job, db, existingID := newDedupTest(t, models.DedupModeMerge, content)
+
+	// The existing todo gains the due date, and only the new step is added under it
+	patch, ok := db.patches[existingID.String()]
+	require.True(t, ok)
+	require.NotNil(t, patch.DueDate)
+	assert.Equal(t, "2025-07-18", patch.DueDate.Format("2006-01-02"))
+
+	require.Len(t, db.todos, 2)
+	assert.Equal(t, "Tanda tangan direktur", db.todos[0].Title)
+	require.NotNil(t, db.todos[0].ParentID)
+	assert.Equal(t, existingID, *db.todos[0].ParentID)
+	assert.Equal(t, "Beli kopi", db.todos[1].Title)
+	assert.Nil(t, db.todos[1].ParentID)
+
+	require.Len(t, job.Result.Duplicates, 1)
+	assert.Equal(t, models.DuplicateMerged, job.Result.Duplicates[0].Action)
+}
+
+func TestProcessJob_DedupMergeRepeatedItems(t *testing.T) {
+	content := "- Kirim laporan 2025-07-18\n  - Tanda tangan direktur\n- Kirim laporan 2025-07-18\n  - Tanda tangan direktur"
+	job, db, existingID := newDedupTest(t, models.DedupModeMerge, content)
+
+	// The second item sees the todo as the first merge left it
+	assert.Equal(t, 1, db.updates)
+	require.Contains(t, db.patches, existingID.String())
+
+	require.Len(t, db.todos, 1)
+	assert.Equal(t, "Tanda tangan direktur", db.todos[0].Title)
+
+	require.Len(t, job.Result.Duplicates, 2)
+	assert.Equal(t, models.DuplicateMerged, job.Result.Duplicates[1].Action)
+}
+
+func TestProcessJob_DedupOffInsertsEverything(t *testing.T) {
+	job, db, _ := newDedupTest(t, models.DedupModeOff, "- Kirim laporan 2025-07-18\n- Beli kopi")
+
+	assert.Len(t, db.todos, 2)
+	assert.Empty(t, job.Result.Duplicates)
+}
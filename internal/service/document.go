@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"todo-agent-backend/pkg/docx"
+	"todo-agent-backend/pkg/pdf"
+	"todo-agent-backend/pkg/rtf"
+)
+
+// documentExtractor reads an uploaded document into model input
+type documentExtractor func(ctx context.Context, filePath string) (*jobInput, error)
+
+// defaultDocumentExtractors returns the extractor for each supported document extension
+func (ps *ProcessingService) defaultDocumentExtractors() map[string]documentExtractor {
+	return map[string]documentExtractor{
+		".txt":  loadPlainText,
+		".pdf":  ps.loadPDF,
+		".docx": loadDOCX,
+		".rtf":  loadRTF,
+	}
+}
+
+// loadPlainText reads a text file as is
+func loadPlainText(_ context.Context, filePath string) (*jobInput, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read text file: %w", err)
+	}
+	return &jobInput{text: string(content)}, nil
+}
+
+// loadPDF extracts the text layer of a PDF upload, keeping page boundaries.
+// Scanned pages are run through OCR when it is enabled. Image-only files
+// that OCR finds no text in go to the vision model page by page, unless
+// the OCR strategy is ocr_only. Encrypted files fail with an error naming
+// the reason.
+func (ps *ProcessingService) loadPDF(ctx context.Context, filePath string) (*jobInput, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF file: %w", err)
+	}
+
+	doc, err := pdf.ExtractText(data)
+	if err != nil && !errors.Is(err, pdf.ErrImageOnly) {
+		return nil, fmt.Errorf("failed to extract PDF text: %w", err)
+	}
+
+	if ps.ocrEnabled() {
+		ps.recognizeScannedPages(ctx, doc)
+	}
+	if doc.HasText() {
+		return &jobInput{text: doc.Text(), pageCount: doc.PageCount()}, nil
+	}
+
+	if ps.ocrEnabled() {
+		if ps.ocrStrategy == OCRStrategyOCROnly {
+			return nil, fmt.Errorf("failed to extract PDF text: %w, and OCR found no text", err)
+		}
+		ps.logger.Warn("OCR found no text in PDF, falling back to vision model")
+	}
+
+	images := ps.scannedPageImages(doc)
+	if len(images) == 0 {
+		return nil, fmt.Errorf("failed to extract PDF text: %w, and no page image can be sent to the vision model", err)
+	}
+
+	return &jobInput{pageImages: images, pageCount: doc.PageCount()}, nil
+}
+
+// loadDOCX extracts paragraphs, list items and tables from a Word document
+func loadDOCX(_ context.Context, filePath string) (*jobInput, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DOCX file: %w", err)
+	}
+
+	text, err := docx.ExtractText(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract DOCX text: %w", err)
+	}
+
+	return &jobInput{text: text}, nil
+}
+
+// loadRTF converts a Rich Text Format document to plain text
+func loadRTF(_ context.Context, filePath string) (*jobInput, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RTF file: %w", err)
+	}
+
+	text, err := rtf.ExtractText(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract RTF text: %w", err)
+	}
+
+	return &jobInput{text: text}, nil
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"todo-agent-backend/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessDocumentFile_UsesRegisteredExtractor(t *testing.T) {
+	ps := NewProcessingService(nil, nil, nil, logger.NewLogger("error", "console"))
+	dir := t.TempDir()
+
+	rtfPath := filepath.Join(dir, "notes.RTF")
+	require.NoError(t, os.WriteFile(rtfPath, []byte(`{\rtf1\ansi Kirim laporan\par}`), 0644))
+
+	input, err := ps.processDocumentFile(context.Background(), rtfPath)
+	require.NoError(t, err)
+	assert.Equal(t, "Kirim laporan", input.text)
+
+	txtPath := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(txtPath, []byte("Beli kopi"), 0644))
+
+	input, err = ps.processDocumentFile(context.Background(), txtPath)
+	require.NoError(t, err)
+	assert.Equal(t, "Beli kopi", input.text)
+}
+
+func TestProcessDocumentFile_UnsupportedFormat(t *testing.T) {
+	ps := NewProcessingService(nil, nil, nil, logger.NewLogger("error", "console"))
+
+	docPath := filepath.Join(t.TempDir(), "notes.doc")
+	require.NoError(t, os.WriteFile(docPath, []byte{0xd0, 0xcf, 0x11, 0xe0}, 0644))
+
+	_, err := ps.processDocumentFile(context.Background(), docPath)
+	assert.EqualError(t, err, "unsupported document format: .doc")
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"todo-agent-backend/pkg/gemini"
+	"todo-agent-backend/pkg/openai"
+	"todo-agent-backend/pkg/prompt"
+	"todo-agent-backend/pkg/retry"
+)
+
+// geminiExtractor adapts the Gemini client to TodoExtractor
+type geminiExtractor struct {
+	*gemini.Client
+}
+
+// NewGeminiExtractor creates a todo extractor backed by Gemini
+func NewGeminiExtractor(client *gemini.Client) TodoExtractor {
+	return geminiExtractor{client}
+}
+
+func (e geminiExtractor) WithRetryCounter(counter *retry.Counter) TodoExtractor {
+	return geminiExtractor{e.Client.WithRetryCounter(counter)}
+}
+
+func (e geminiExtractor) WithRepairCounter(counter *retry.Counter) TodoExtractor {
+	return geminiExtractor{e.Client.WithRepairCounter(counter)}
+}
+
+func (e geminiExtractor) WithPrompt(p prompt.Prompt) TodoExtractor {
+	return geminiExtractor{e.Client.WithPrompt(p)}
+}
+
+// openAIExtractor adapts the OpenAI-compatible client to TodoExtractor
+type openAIExtractor struct {
+	*openai.Client
+}
+
+// NewOpenAIExtractor creates a todo extractor backed by an OpenAI-compatible chat completions API
+func NewOpenAIExtractor(client *openai.Client) TodoExtractor {
+	return openAIExtractor{client}
+}
+
+func (e openAIExtractor) WithRetryCounter(counter *retry.Counter) TodoExtractor {
+	return openAIExtractor{e.Client.WithRetryCounter(counter)}
+}
+
+func (e openAIExtractor) WithRepairCounter(counter *retry.Counter) TodoExtractor {
+	return openAIExtractor{e.Client.WithRepairCounter(counter)}
+}
+
+func (e openAIExtractor) WithPrompt(p prompt.Prompt) TodoExtractor {
+	return openAIExtractor{e.Client.WithPrompt(p)}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/pkg/prompt"
+	"todo-agent-backend/pkg/retry"
+)
+
+var (
+	listMarker = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s*`)
+	isoDate    = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+)
+
+// FakeExtractor is a deterministic TodoExtractor for tests. Every
+// non-empty line of text becomes a todo, with list markers removed and
+// the first YYYY-MM-DD date on the line used as the due date and the
+// line itself as the source excerpt. Indented
+// lines become subtasks of the todo above them. Images yield
+// ImageTodos. When Err is set every call fails with it.
+type FakeExtractor struct {
+	ImageTodos []models.TodoItem
+	Err        error
+
+	counter *retry.Counter
+}
+
+// ExtractTodos derives todos from the lines of text
+func (f *FakeExtractor) ExtractTodos(ctx context.Context, text string) ([]models.TodoItem, error) {
+	f.counter.Add(1)
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	todos := []models.TodoItem{}
+	for _, line := range strings.Split(text, "\n") {
+		title := strings.TrimSpace(listMarker.ReplaceAllString(line, ""))
+		if title == "" {
+			continue
+		}
+
+		item := models.TodoItem{
+			Title:  title,
+			Source: &models.SourceSpan{Text: title},
+		}
+		if date := isoDate.FindString(title); date != "" {
+			item.DueDate = &date
+		}
+
+		indented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+		if indented && len(todos) > 0 {
+			parent := &todos[len(todos)-1]
+			parent.Subtasks = append(parent.Subtasks, item)
+			continue
+		}
+		todos = append(todos, item)
+	}
+
+	return todos, nil
+}
+
+// ExtractTodosFromImage returns ImageTodos
+func (f *FakeExtractor) ExtractTodosFromImage(ctx context.Context, image []byte, mimeType string) ([]models.TodoItem, error) {
+	f.counter.Add(1)
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	return append([]models.TodoItem{}, f.ImageTodos...), nil
+}
+
+// Model returns "fake"
+func (f *FakeExtractor) Model() string {
+	return "fake"
+}
+
+// WithRetryCounter returns a copy of the fake that counts every call in counter
+func (f *FakeExtractor) WithRetryCounter(counter *retry.Counter) TodoExtractor {
+	clone := *f
+	clone.counter = counter
+	return &clone
+}
+
+// WithRepairCounter returns a copy of the fake. The fake never produces
+// invalid output, so no repair rounds are recorded.
+func (f *FakeExtractor) WithRepairCounter(counter *retry.Counter) TodoExtractor {
+	clone := *f
+	return &clone
+}
+
+// WithPrompt returns a copy of the fake. The fake does not use prompts.
+func (f *FakeExtractor) WithPrompt(p prompt.Prompt) TodoExtractor {
+	clone := *f
+	return &clone
+}
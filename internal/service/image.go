@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"image/gif"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"todo-agent-backend/pkg/bmp"
+)
+
+// visionMimeTypes maps image extensions to the MIME types Gemini accepts inline
+var visionMimeTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+	".gif":  "image/gif",
+	".bmp":  "image/bmp",
+}
+
+// loadImage reads an uploaded image and returns it in a format the vision
+// model accepts. GIFs and BMPs are converted to PNG since Gemini does not
+// read them.
+func loadImage(filePath string) ([]byte, string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image file: %w", err)
+	}
+
+	mimeType, ok := visionMimeTypes[strings.ToLower(filepath.Ext(filePath))]
+	if !ok {
+		mimeType = http.DetectContentType(data)
+	}
+
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/webp":
+		return data, mimeType, nil
+	case "image/gif":
+		converted, err := gifToPNG(data)
+		if err != nil {
+			return nil, "", err
+		}
+		return converted, "image/png", nil
+	case "image/bmp":
+		converted, err := bmpToPNG(data)
+		if err != nil {
+			return nil, "", err
+		}
+		return converted, "image/png", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported image format: %s", mimeType)
+	}
+}
+
+// gifToPNG re-encodes the first frame of a GIF as PNG
+func gifToPNG(data []byte) ([]byte, error) {
+	img, err := gif.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode gif: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// bmpToPNG re-encodes a BMP as PNG
+func bmpToPNG(data []byte) ([]byte, error) {
+	img, err := bmp.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode bmp: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
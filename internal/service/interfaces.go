@@ -1,16 +1,67 @@
 package service
 
-import "todo-agent-backend/internal/models"
+import (
+	"context"
+
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/pkg/prompt"
+	"todo-agent-backend/pkg/retry"
+)
 
 // ProcessingServiceInterface defines the interface for processing service
 type ProcessingServiceInterface interface {
-	ProcessJob(job *models.Job)
+	ProcessJob(ctx context.Context, job *models.Job)
 }
 
 // JobServiceInterface defines the interface for job service
 type JobServiceInterface interface {
 	SubmitJob(job *models.Job) error
+	// SubmitIdempotentJob returns the original job when the job's idempotency key was already used, nil otherwise
+	SubmitIdempotentJob(job *models.Job) (*models.Job, error)
 	GetJob(jobID string) (*models.Job, error)
 	UpdateJob(jobID string, status models.JobStatusEnum, result *models.ProcessingResult, errorMsg string) error
+	UpdateProgress(jobID string, progress models.JobProgress) error
+	UpdateResult(jobID string, result *models.ProcessingResult) error
 	ListJobs(userID string) []*models.Job
+	ListJobsByStatus(statuses ...models.JobStatusEnum) ([]*models.Job, error)
+}
+
+// JobCommitter saves the todos of preview jobs
+type JobCommitter interface {
+	CommitJob(ctx context.Context, jobID string, todos []models.TodoItem) (*models.Job, error)
+}
+
+// JobQueueInterface defines the interface for queueing jobs for processing
+type JobQueueInterface interface {
+	Enqueue(job *models.Job) error
+}
+
+// OCREngine recognizes text in images
+type OCREngine interface {
+	Recognize(ctx context.Context, image []byte, format string) (string, error)
+}
+
+// ResultCache keeps extraction results by a hash of their input
+type ResultCache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte) error
+}
+
+// ObjectStore keeps copies of job inputs and returns a URL for each
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) (string, error)
+}
+
+// TodoExtractor extracts todo items from text or images with a language model
+type TodoExtractor interface {
+	ExtractTodos(ctx context.Context, text string) ([]models.TodoItem, error)
+	ExtractTodosFromImage(ctx context.Context, image []byte, mimeType string) ([]models.TodoItem, error)
+	// Model names the model that extracts the todos
+	Model() string
+	// WithRetryCounter returns a copy that records every request attempt in counter
+	WithRetryCounter(counter *retry.Counter) TodoExtractor
+	// WithRepairCounter returns a copy that records every repair round in counter
+	WithRepairCounter(counter *retry.Counter) TodoExtractor
+	// WithPrompt returns a copy that renders its instructions from p
+	WithPrompt(p prompt.Prompt) TodoExtractor
 }
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// orphanGracePeriod protects uploads that were saved but whose job has not been submitted yet
+const orphanGracePeriod = 5 * time.Minute
+
+// CleanupReport describes what a single cleanup run removed
+type CleanupReport struct {
+	ExpiredJobs   int
+	OrphanedFiles int
+	FreedBytes    int64
+}
+
+// Janitor periodically evicts expired jobs and deletes orphaned temp files
+type Janitor struct {
+	jobService *JobService
+	tempDir    string
+	interval   time.Duration
+	maxAge     time.Duration
+	logger     *logger.Logger
+	stop       chan struct{}
+	wg         sync.WaitGroup
+	totals     CleanupReport
+}
+
+// NewJanitor creates a new janitor
+func NewJanitor(jobService *JobService, tempDir string, interval, maxAge time.Duration, logger *logger.Logger) *Janitor {
+	return &Janitor{
+		jobService: jobService,
+		tempDir:    tempDir,
+		interval:   interval,
+		maxAge:     maxAge,
+		logger:     logger,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start runs cleanup in the background every interval
+func (j *Janitor) Start() {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				j.RunOnce()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+
+	j.logger.Info("Janitor started",
+		zap.Duration("interval", j.interval),
+		zap.Duration("max_age", j.maxAge),
+		zap.String("temp_dir", j.tempDir),
+	)
+}
+
+// Stop stops the background cleanup and waits for a running pass to finish
+func (j *Janitor) Stop() {
+	close(j.stop)
+	j.wg.Wait()
+}
+
+// RunOnce performs a single cleanup pass
+func (j *Janitor) RunOnce() CleanupReport {
+	var report CleanupReport
+
+	report.ExpiredJobs = j.jobService.CleanupOldJobs(j.maxAge)
+	report.OrphanedFiles, report.FreedBytes = j.removeOrphanedFiles()
+
+	j.totals.ExpiredJobs += report.ExpiredJobs
+	j.totals.OrphanedFiles += report.OrphanedFiles
+	j.totals.FreedBytes += report.FreedBytes
+
+	// Passes that found nothing to remove are not worth a log line
+	if report.ExpiredJobs == 0 && report.OrphanedFiles == 0 {
+		return report
+	}
+
+	j.logger.Info("Cleanup completed",
+		zap.Int("expired_jobs", report.ExpiredJobs),
+		zap.Int("orphaned_files", report.OrphanedFiles),
+		zap.Int64("freed_bytes", report.FreedBytes),
+		zap.Int("total_expired_jobs", j.totals.ExpiredJobs),
+		zap.Int("total_orphaned_files", j.totals.OrphanedFiles),
+		zap.Int64("total_freed_bytes", j.totals.FreedBytes),
+	)
+
+	return report
+}
+
+// removeOrphanedFiles deletes files in the temp directory that no unfinished job references
+func (j *Janitor) removeOrphanedFiles() (int, int64) {
+	entries, err := os.ReadDir(j.tempDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			j.logger.Warn("Failed to read temp directory",
+				zap.String("temp_dir", j.tempDir),
+				zap.Error(err))
+		}
+		return 0, 0
+	}
+
+	liveJobs, err := j.jobService.ListJobsByStatus(models.JobStatusPending, models.JobStatusProcessing)
+	if err != nil {
+		j.logger.Warn("Failed to list unfinished jobs", zap.Error(err))
+		return 0, 0
+	}
+
+	referenced := make(map[string]bool, len(liveJobs))
+	for _, job := range liveJobs {
+		if job.FilePath != "" {
+			referenced[filepath.Clean(job.FilePath)] = true
+		}
+	}
+
+	cutoff := time.Now().Add(-orphanGracePeriod)
+	removed := 0
+	var freed int64
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(j.tempDir, entry.Name())
+		if referenced[path] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		if err := os.Remove(path); err != nil {
+			j.logger.Warn("Failed to remove orphaned file",
+				zap.String("file_path", path),
+				zap.Error(err))
+			continue
+		}
+
+		j.logger.Debug("Removed orphaned file", zap.String("file_path", path))
+		removed++
+		freed += info.Size()
+	}
+
+	return removed, freed
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeAgedFile(t *testing.T, path string, age time.Duration) {
+	require.NoError(t, os.WriteFile(path, []byte("notes"), 0644))
+	modTime := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestJanitor_RunOnce(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	tempDir := t.TempDir()
+
+	liveFile := filepath.Join(tempDir, "live.pdf")
+	orphanFile := filepath.Join(tempDir, "orphan.pdf")
+	freshFile := filepath.Join(tempDir, "fresh.pdf")
+	writeAgedFile(t, liveFile, time.Hour)
+	writeAgedFile(t, orphanFile, time.Hour)
+	writeAgedFile(t, freshFile, time.Second)
+
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, jobService.SubmitJob(&models.Job{ID: "live", Type: "document", FilePath: liveFile, Status: models.JobStatusPending, CreatedAt: old}))
+	require.NoError(t, jobService.SubmitJob(&models.Job{ID: "expired", Type: "text", Status: models.JobStatusCompleted, CreatedAt: old}))
+	require.NoError(t, jobService.SubmitJob(&models.Job{ID: "recent", Type: "text", Status: models.JobStatusFailed, CreatedAt: time.Now()}))
+
+	janitor := NewJanitor(jobService, tempDir, time.Hour, 24*time.Hour, log)
+	report := janitor.RunOnce()
+
+	assert.Equal(t, 1, report.ExpiredJobs)
+	assert.Equal(t, 1, report.OrphanedFiles)
+	assert.Equal(t, int64(len("notes")), report.FreedBytes)
+
+	_, err := jobService.GetJob("expired")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	_, err = jobService.GetJob("live")
+	assert.NoError(t, err)
+
+	assert.FileExists(t, liveFile)
+	assert.FileExists(t, freshFile)
+	assert.NoFileExists(t, orphanFile)
+}
+
+func TestJanitor_StartStop(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	janitor := NewJanitor(NewJobService(repository.NewMemoryJobStore(), log), t.TempDir(), time.Millisecond, time.Hour, log)
+
+	janitor.Start()
+	time.Sleep(5 * time.Millisecond)
+	janitor.Stop()
+}
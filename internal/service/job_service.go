@@ -2,66 +2,198 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"todo-agent-backend/internal/logger"
 	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
 
 	"go.uber.org/zap"
 )
 
 var (
-	ErrJobNotFound = errors.New("job not found")
+	ErrJobNotFound          = repository.ErrJobNotFound
+	ErrInvalidTransition    = errors.New("invalid job status transition")
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used with a different request")
 )
 
+// DefaultIdempotencyWindow is how long an idempotency key maps to its job
+const DefaultIdempotencyWindow = 24 * time.Hour
+
 // JobService manages job lifecycle
 type JobService struct {
-	jobs   map[string]*models.Job
-	mutex  sync.RWMutex
-	logger *logger.Logger
+	store             repository.JobStore
+	mutex             sync.Mutex
+	idempotencyWindow time.Duration
+	logger            *logger.Logger
+
+	// keys maps idempotency keys to the latest job submitted with them.
+	// It is loaded from the store on first use and guarded by mutex.
+	keys map[idempotencyKey]string
+}
+
+// idempotencyKey identifies an idempotency key, which is unique per user
+type idempotencyKey struct {
+	userID string
+	key    string
 }
 
-// NewJobService creates a new job service
-func NewJobService(logger *logger.Logger) *JobService {
+// NewJobService creates a new job service backed by the given store
+func NewJobService(store repository.JobStore, logger *logger.Logger) *JobService {
 	return &JobService{
-		jobs:   make(map[string]*models.Job),
-		logger: logger,
+		store:             store,
+		idempotencyWindow: DefaultIdempotencyWindow,
+		logger:            logger,
 	}
 }
 
+// SetIdempotencyWindow sets how long after a job is created its
+// idempotency key keeps returning it
+func (js *JobService) SetIdempotencyWindow(window time.Duration) {
+	js.idempotencyWindow = window
+}
+
 // SubmitJob submits a new job
 func (js *JobService) SubmitJob(job *models.Job) error {
-	js.mutex.Lock()
-	defer js.mutex.Unlock()
+	if err := js.store.Save(job); err != nil {
+		return err
+	}
+
+	if job.IdempotencyKey != "" {
+		js.mutex.Lock()
+		js.indexKey(job)
+		js.mutex.Unlock()
+	}
 
-	js.jobs[job.ID] = job
 	js.logger.Info("Job submitted", zap.String("job_id", job.ID))
 	
 	return nil
 }
 
-// GetJob retrieves a job by ID
-func (js *JobService) GetJob(jobID string) (*models.Job, error) {
-	js.mutex.RLock()
-	defer js.mutex.RUnlock()
+// SubmitIdempotentJob submits job unless its user already submitted a job
+// with the same idempotency key within the idempotency window. It then
+// returns that original job instead, or ErrIdempotencyKeyReused when the
+// original was submitted with a different payload. Jobs that were rejected
+// before their first attempt do not hold on to their key.
+func (js *JobService) SubmitIdempotentJob(job *models.Job) (*models.Job, error) {
+	// Serialized with other writes so concurrent retries cannot both submit
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	existing, err := js.jobForKey(job.UserID, job.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil && js.holdsKey(existing) {
+		if existing.PayloadHash != job.PayloadHash {
+			return existing, ErrIdempotencyKeyReused
+		}
+		return existing, nil
+	}
+
+	if err := js.store.Save(job); err != nil {
+		return nil, err
+	}
+	js.indexKey(job)
+
+	js.logger.Info("Job submitted",
+		zap.String("job_id", job.ID),
+		zap.Bool("idempotent", true))
+
+	return nil, nil
+}
+
+// holdsKey reports whether job still holds its idempotency key
+func (js *JobService) holdsKey(job *models.Job) bool {
+	if job.CreatedAt.Before(time.Now().Add(-js.idempotencyWindow)) {
+		return false
+	}
+	return job.Status != models.JobStatusFailed || job.Attempts > 0
+}
+
+// jobForKey returns the latest job submitted with the key, or nil. The
+// caller must hold mutex.
+func (js *JobService) jobForKey(userID, key string) (*models.Job, error) {
+	if js.keys == nil {
+		if err := js.loadKeys(); err != nil {
+			return nil, err
+		}
+	}
+
+	jobID, ok := js.keys[idempotencyKey{userID, key}]
+	if !ok {
+		return nil, nil
+	}
+
+	job, err := js.store.Get(jobID)
+	if errors.Is(err, ErrJobNotFound) {
+		delete(js.keys, idempotencyKey{userID, key})
+		return nil, nil
+	}
+	return job, err
+}
 
-	job, exists := js.jobs[jobID]
-	if !exists {
-		return nil, ErrJobNotFound
+// loadKeys builds the idempotency key index from the stored jobs
+func (js *JobService) loadKeys() error {
+	jobs, err := js.store.List()
+	if err != nil {
+		return err
 	}
 
-	return job, nil
+	latest := make(map[idempotencyKey]*models.Job)
+	for _, job := range jobs {
+		if job.IdempotencyKey == "" {
+			continue
+		}
+		k := idempotencyKey{job.UserID, job.IdempotencyKey}
+		if current, ok := latest[k]; !ok || job.CreatedAt.After(current.CreatedAt) {
+			latest[k] = job
+		}
+	}
+
+	js.keys = make(map[idempotencyKey]string, len(latest))
+	for k, job := range latest {
+		js.keys[k] = job.ID
+	}
+	return nil
 }
 
-// UpdateJob updates job status and result
+// indexKey records job as the latest job for its idempotency key once
+// the index is loaded. The caller must hold mutex.
+func (js *JobService) indexKey(job *models.Job) {
+	if js.keys != nil {
+		js.keys[idempotencyKey{job.UserID, job.IdempotencyKey}] = job.ID
+	}
+}
+
+// GetJob retrieves a job by ID
+func (js *JobService) GetJob(jobID string) (*models.Job, error) {
+	return js.store.Get(jobID)
+}
+
+// UpdateJob moves a job to a new status and records its result.
+// It returns ErrInvalidTransition when the job cannot move to status,
+// e.g. because it already reached a terminal state.
 func (js *JobService) UpdateJob(jobID string, status models.JobStatusEnum, result *models.ProcessingResult, errorMsg string) error {
+	// Serialize read-modify-write cycles so concurrent updates are not lost
 	js.mutex.Lock()
 	defer js.mutex.Unlock()
 
-	job, exists := js.jobs[jobID]
-	if !exists {
-		return ErrJobNotFound
+	job, err := js.store.Get(jobID)
+	if err != nil {
+		return err
+	}
+
+	if !job.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, job.Status, status)
+	}
+
+	// Every move into processing is a new attempt
+	if status == models.JobStatusProcessing {
+		job.Attempts++
 	}
 
 	job.Status = status
@@ -69,6 +201,10 @@ func (js *JobService) UpdateJob(jobID string, status models.JobStatusEnum, resul
 	job.Error = errorMsg
 	job.UpdatedAt = time.Now()
 
+	if err := js.store.Save(job); err != nil {
+		return err
+	}
+
 	js.logger.Info("Job updated",
 		zap.String("job_id", jobID),
 		zap.String("status", string(status)),
@@ -77,13 +213,52 @@ func (js *JobService) UpdateJob(jobID string, status models.JobStatusEnum, resul
 	return nil
 }
 
+// UpdateResult replaces the result of a completed job without changing its status
+func (js *JobService) UpdateResult(jobID string, result *models.ProcessingResult) error {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	job, err := js.store.Get(jobID)
+	if err != nil {
+		return err
+	}
+
+	if job.Status != models.JobStatusCompleted {
+		return fmt.Errorf("%w: %s job has no result to replace", ErrInvalidTransition, job.Status)
+	}
+
+	job.Result = result
+	job.UpdatedAt = time.Now()
+
+	return js.store.Save(job)
+}
+
+// UpdateProgress replaces the progress counters of a job without changing its status
+func (js *JobService) UpdateProgress(jobID string, progress models.JobProgress) error {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	job, err := js.store.Get(jobID)
+	if err != nil {
+		return err
+	}
+
+	job.Progress = progress
+	job.UpdatedAt = time.Now()
+
+	return js.store.Save(job)
+}
+
 // ListJobs returns all jobs for a user
 func (js *JobService) ListJobs(userID string) []*models.Job {
-	js.mutex.RLock()
-	defer js.mutex.RUnlock()
+	jobs, err := js.store.List()
+	if err != nil {
+		js.logger.Error("Failed to list jobs", zap.Error(err))
+		return nil
+	}
 
 	var userJobs []*models.Job
-	for _, job := range js.jobs {
+	for _, job := range jobs {
 		if job.UserID == userID {
 			userJobs = append(userJobs, job)
 		}
@@ -92,22 +267,70 @@ func (js *JobService) ListJobs(userID string) []*models.Job {
 	return userJobs
 }
 
-// CleanupOldJobs removes jobs older than the specified duration
-func (js *JobService) CleanupOldJobs(maxAge time.Duration) {
-	js.mutex.Lock()
-	defer js.mutex.Unlock()
+// ListJobsByStatus returns all jobs in any of the given statuses
+func (js *JobService) ListJobsByStatus(statuses ...models.JobStatusEnum) ([]*models.Job, error) {
+	jobs, err := js.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []*models.Job
+	for _, job := range jobs {
+		for _, status := range statuses {
+			if job.Status == status {
+				matched = append(matched, job)
+				break
+			}
+		}
+	}
+
+	return matched, nil
+}
+
+// CleanupOldJobs removes finished jobs older than the specified duration
+// and returns how many were removed. Unfinished jobs are kept so they can
+// still be processed or recovered.
+func (js *JobService) CleanupOldJobs(maxAge time.Duration) int {
+	jobs, err := js.store.List()
+	if err != nil {
+		js.logger.Error("Failed to list jobs for cleanup", zap.Error(err))
+		return 0
+	}
 
 	cutoff := time.Now().Add(-maxAge)
 	deleted := 0
 
-	for jobID, job := range js.jobs {
-		if job.CreatedAt.Before(cutoff) {
-			delete(js.jobs, jobID)
+	for _, job := range jobs {
+		if job.Status.IsTerminal() && job.CreatedAt.Before(cutoff) {
+			if err := js.store.Delete(job.ID); err != nil && err != ErrJobNotFound {
+				js.logger.Warn("Failed to delete old job",
+					zap.String("job_id", job.ID),
+					zap.Error(err))
+				continue
+			}
 			deleted++
+
+			if job.IdempotencyKey != "" {
+				js.releaseKey(job)
+			}
 		}
 	}
 
 	if deleted > 0 {
 		js.logger.Info("Cleaned up old jobs", zap.Int("deleted_count", deleted))
 	}
+
+	return deleted
+}
+
+// releaseKey drops the idempotency key of a deleted job from the index
+// unless a later job holds it
+func (js *JobService) releaseKey(job *models.Job) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	k := idempotencyKey{job.UserID, job.IdempotencyKey}
+	if js.keys[k] == job.ID {
+		delete(js.keys, k)
+	}
 }
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobService_UpdateJobEnforcesTransitions(t *testing.T) {
+	jobService := NewJobService(repository.NewMemoryJobStore(), logger.NewLogger("error", "console"))
+	require.NoError(t, jobService.SubmitJob(newTestJob("job-1")))
+
+	// pending -> completed skips processing
+	err := jobService.UpdateJob("job-1", models.JobStatusCompleted, &models.ProcessingResult{}, "")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	require.NoError(t, jobService.UpdateJob("job-1", models.JobStatusProcessing, nil, ""))
+	require.NoError(t, jobService.UpdateJob("job-1", models.JobStatusFailed, nil, "job timed out"))
+
+	// A late result cannot overwrite a terminal state
+	err = jobService.UpdateJob("job-1", models.JobStatusCompleted, &models.ProcessingResult{}, "")
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+
+	job, err := jobService.GetJob("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusFailed, job.Status)
+	assert.Equal(t, "job timed out", job.Error)
+	assert.Equal(t, 1, job.Attempts)
+}
+
+func TestJobService_ConcurrentUpdates(t *testing.T) {
+	jobService := NewJobService(repository.NewMemoryJobStore(), logger.NewLogger("error", "console"))
+	require.NoError(t, jobService.SubmitJob(newTestJob("job-1")))
+
+	// Racing updates are serialized and each one sees a legal source status
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	succeeded := 0
+	for _, status := range []models.JobStatusEnum{models.JobStatusProcessing, models.JobStatusFailed, models.JobStatusCancelled} {
+		wg.Add(1)
+		go func(status models.JobStatusEnum) {
+			defer wg.Done()
+			if jobService.UpdateJob("job-1", status, nil, "") == nil {
+				mutex.Lock()
+				succeeded++
+				mutex.Unlock()
+			}
+			_, _ = jobService.GetJob("job-1")
+		}(status)
+	}
+	wg.Wait()
+
+	job, err := jobService.GetJob("job-1")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, succeeded, 1)
+	assert.NotEqual(t, models.JobStatusPending, job.Status)
+}
+
+func TestJobService_SubmitIdempotentJob(t *testing.T) {
+	jobService := NewJobService(repository.NewMemoryJobStore(), logger.NewLogger("error", "console"))
+	jobService.SetIdempotencyWindow(time.Hour)
+
+	idempotentJob := func(id, hash string, createdAt time.Time) *models.Job {
+		job := newTestJob(id)
+		job.IdempotencyKey = "key-1"
+		job.PayloadHash = hash
+		job.CreatedAt = createdAt
+		return job
+	}
+
+	original, err := jobService.SubmitIdempotentJob(idempotentJob("job-1", "hash-a", time.Now()))
+	require.NoError(t, err)
+	assert.Nil(t, original)
+
+	original, err = jobService.SubmitIdempotentJob(idempotentJob("job-2", "hash-a", time.Now()))
+	require.NoError(t, err)
+	require.NotNil(t, original)
+	assert.Equal(t, "job-1", original.ID)
+
+	_, err = jobService.SubmitIdempotentJob(idempotentJob("job-3", "hash-b", time.Now()))
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	_, err = jobService.GetJob("job-2")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestJobService_SubmitIdempotentJobReleasesKey(t *testing.T) {
+	jobService := NewJobService(repository.NewMemoryJobStore(), logger.NewLogger("error", "console"))
+	jobService.SetIdempotencyWindow(time.Hour)
+
+	// A key expires with the window
+	expired := newTestJob("job-1")
+	expired.IdempotencyKey = "key-1"
+	expired.CreatedAt = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, jobService.SubmitJob(expired))
+
+	// A job rejected before it was ever attempted does not hold its key
+	rejected := newTestJob("job-2")
+	rejected.IdempotencyKey = "key-1"
+	rejected.CreatedAt = time.Now()
+	require.NoError(t, jobService.SubmitJob(rejected))
+	require.NoError(t, jobService.UpdateJob("job-2", models.JobStatusFailed, nil, "queue is full"))
+
+	retry := newTestJob("job-3")
+	retry.IdempotencyKey = "key-1"
+	retry.CreatedAt = time.Now()
+	original, err := jobService.SubmitIdempotentJob(retry)
+	require.NoError(t, err)
+	assert.Nil(t, original)
+
+	_, err = jobService.GetJob("job-3")
+	assert.NoError(t, err)
+}
+
+// countingJobStore counts List calls
+type countingJobStore struct {
+	repository.JobStore
+	lists int
+}
+
+func (s *countingJobStore) List() ([]*models.Job, error) {
+	s.lists++
+	return s.JobStore.List()
+}
+
+func TestJobService_SubmitIdempotentJobUsesKeyIndex(t *testing.T) {
+	store := &countingJobStore{JobStore: repository.NewMemoryJobStore()}
+	jobService := NewJobService(store, logger.NewLogger("error", "console"))
+	jobService.SetIdempotencyWindow(time.Hour)
+
+	// Jobs stored before the index is loaded are found
+	stored := newTestJob("job-1")
+	stored.IdempotencyKey = "key-1"
+	stored.CreatedAt = time.Now()
+	require.NoError(t, store.Save(stored))
+
+	for i, id := range []string{"job-2", "job-3", "job-4"} {
+		job := newTestJob(id)
+		job.IdempotencyKey = fmt.Sprintf("key-%d", i%2+1)
+		job.CreatedAt = time.Now()
+		_, err := jobService.SubmitIdempotentJob(job)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, store.lists)
+
+	// job-2 and job-4 replay job-1, job-3 is new
+	_, err := jobService.GetJob("job-2")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	_, err = jobService.GetJob("job-4")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	_, err = jobService.GetJob("job-3")
+	assert.NoError(t, err)
+
+	// A deleted job no longer holds its key
+	require.NoError(t, store.Delete("job-1"))
+	job := newTestJob("job-5")
+	job.IdempotencyKey = "key-1"
+	job.CreatedAt = time.Now()
+	original, err := jobService.SubmitIdempotentJob(job)
+	require.NoError(t, err)
+	assert.Nil(t, original)
+}
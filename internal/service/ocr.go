@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"todo-agent-backend/pkg/pdf"
+
+	"go.uber.org/zap"
+)
+
+// OCRStrategy selects how image content is turned into model input
+type OCRStrategy string
+
+const (
+	// OCRStrategyOCROnly sends only OCR text to the model and fails when OCR finds nothing
+	OCRStrategyOCROnly OCRStrategy = "ocr_only"
+	// OCRStrategyVisionOnly sends images to the vision model and never runs OCR
+	OCRStrategyVisionOnly OCRStrategy = "vision_only"
+	// OCRStrategyOCRThenVision runs OCR first and falls back to the vision model
+	OCRStrategyOCRThenVision OCRStrategy = "ocr_then_vision"
+)
+
+// imageFormats maps image MIME types to the file formats passed to the OCR engine
+var imageFormats = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/webp": "webp",
+}
+
+// visionFormats maps the image formats found in PDFs to the MIME types
+// the vision model accepts
+var visionFormats = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+}
+
+// SetOCR enables the OCR stage for image uploads and scanned PDF pages
+func (ps *ProcessingService) SetOCR(engine OCREngine, strategy OCRStrategy) {
+	ps.ocr = engine
+	ps.ocrStrategy = strategy
+}
+
+// ocrEnabled reports whether images should be run through OCR
+func (ps *ProcessingService) ocrEnabled() bool {
+	return ps.ocr != nil && ps.ocrStrategy != OCRStrategyVisionOnly
+}
+
+// recognizeImage turns an uploaded image into model input according to the OCR strategy
+func (ps *ProcessingService) recognizeImage(ctx context.Context, image []byte, mimeType string) (*jobInput, error) {
+	vision := &jobInput{image: image, mimeType: mimeType}
+	if !ps.ocrEnabled() {
+		return vision, nil
+	}
+
+	text, err := ps.ocr.Recognize(ctx, image, imageFormats[mimeType])
+	if err == nil && strings.TrimSpace(text) != "" {
+		return &jobInput{text: text}, nil
+	}
+
+	if ps.ocrStrategy == OCRStrategyOCROnly {
+		if err != nil {
+			return nil, fmt.Errorf("OCR failed: %w", err)
+		}
+		return nil, fmt.Errorf("OCR found no text in image")
+	}
+
+	ps.logger.Warn("OCR produced no text, falling back to vision model", zap.Error(err))
+	return vision, nil
+}
+
+// recognizeScannedPages fills in the text of PDF pages that have images but no text layer
+func (ps *ProcessingService) recognizeScannedPages(ctx context.Context, doc *pdf.Document) {
+	for i := range doc.Pages {
+		page := &doc.Pages[i]
+		if strings.TrimSpace(page.Text) != "" {
+			continue
+		}
+
+		var texts []string
+		for _, img := range page.Images {
+			if ctx.Err() != nil {
+				return
+			}
+
+			data, format, err := img.Extract()
+			if err != nil {
+				ps.logger.Debug("Skipping PDF image that cannot be extracted",
+					zap.Int("page", page.Number),
+					zap.Error(err))
+				continue
+			}
+
+			text, err := ps.ocr.Recognize(ctx, data, format)
+			if err != nil {
+				ps.logger.Warn("OCR failed for PDF page",
+					zap.Int("page", page.Number),
+					zap.Error(err))
+				continue
+			}
+			if text = strings.TrimSpace(text); text != "" {
+				texts = append(texts, text)
+			}
+		}
+
+		page.Text = strings.Join(texts, "\n")
+	}
+}
+
+// scannedPageImages returns the images of PDF pages without text in a
+// format the vision model accepts
+func (ps *ProcessingService) scannedPageImages(doc *pdf.Document) []pageImage {
+	var images []pageImage
+	for _, page := range doc.Pages {
+		if strings.TrimSpace(page.Text) != "" {
+			continue
+		}
+
+		for _, img := range page.Images {
+			data, format, err := img.Extract()
+			if err == nil && visionFormats[format] == "" {
+				err = fmt.Errorf("unsupported image format %s", format)
+			}
+			if err != nil {
+				ps.logger.Debug("Skipping PDF image that cannot be sent to the vision model",
+					zap.Int("page", page.Number),
+					zap.Error(err))
+				continue
+			}
+
+			images = append(images, pageImage{data: data, mimeType: visionFormats[format]})
+		}
+	}
+	return images
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOCR returns a fixed result and records the formats it was asked to read
+type fakeOCR struct {
+	text    string
+	err     error
+	formats []string
+}
+
+func (f *fakeOCR) Recognize(ctx context.Context, image []byte, format string) (string, error) {
+	f.formats = append(f.formats, format)
+	return f.text, f.err
+}
+
+func newOCRTestService(engine OCREngine, strategy OCRStrategy) *ProcessingService {
+	ps := NewProcessingService(nil, nil, nil, logger.NewLogger("error", "console"))
+	ps.SetOCR(engine, strategy)
+	return ps
+}
+
+func TestRecognizeImage_Strategies(t *testing.T) {
+	image := []byte("png data")
+
+	tests := []struct {
+		name      string
+		strategy  OCRStrategy
+		ocr       *fakeOCR
+		wantText  string
+		wantImage bool
+		wantErr   bool
+	}{
+		{name: "ocr only", strategy: OCRStrategyOCROnly, ocr: &fakeOCR{text: "Beli susu"}, wantText: "Beli susu"},
+		{name: "ocr only without text", strategy: OCRStrategyOCROnly, ocr: &fakeOCR{text: "  "}, wantErr: true},
+		{name: "ocr only with failure", strategy: OCRStrategyOCROnly, ocr: &fakeOCR{err: errors.New("boom")}, wantErr: true},
+		{name: "vision only", strategy: OCRStrategyVisionOnly, ocr: &fakeOCR{text: "ignored"}, wantImage: true},
+		{name: "ocr then vision", strategy: OCRStrategyOCRThenVision, ocr: &fakeOCR{text: "Beli susu"}, wantText: "Beli susu"},
+		{name: "ocr then vision fallback", strategy: OCRStrategyOCRThenVision, ocr: &fakeOCR{err: errors.New("boom")}, wantImage: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := newOCRTestService(tt.ocr, tt.strategy)
+
+			input, err := ps.recognizeImage(context.Background(), image, "image/png")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantText, input.text)
+			if tt.wantImage {
+				assert.Equal(t, image, input.image)
+				assert.Equal(t, "image/png", input.mimeType)
+			} else {
+				assert.Nil(t, input.image)
+			}
+		})
+	}
+}
+
+func TestRecognizeImage_Disabled(t *testing.T) {
+	ps := NewProcessingService(nil, nil, nil, logger.NewLogger("error", "console"))
+
+	input, err := ps.recognizeImage(context.Background(), []byte("jpeg data"), "image/jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("jpeg data"), input.image)
+}
+
+// scannedPDF builds a one page PDF whose only content is a JPEG image
+func scannedPDF() []byte {
+	jpeg := []byte{0xff, 0xd8, 0xff, 0xd9}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /XObject << /Im1 5 0 R >> >> >>",
+		"<< /Length 7 >>\nstream\n/Im1 Do\nendstream",
+		fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n%s\nendstream", len(jpeg), jpeg),
+	}
+
+	data := []byte("%PDF-1.7\n")
+	for i, body := range objects {
+		data = append(data, fmt.Sprintf("%d 0 obj\n%s\nendobj\n", i+1, body)...)
+	}
+	return append(data, "trailer\n<< /Root 1 0 R >>\n%%EOF\n"...)
+}
+
+func TestLoadPDF_OCRsScannedPages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.pdf")
+	require.NoError(t, os.WriteFile(path, scannedPDF(), 0644))
+
+	ocr := &fakeOCR{text: "Rapat jam 9"}
+	ps := newOCRTestService(ocr, OCRStrategyOCRThenVision)
+
+	input, err := ps.processDocumentFile(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, "--- Page 1 ---\nRapat jam 9", input.text)
+	assert.Equal(t, 1, input.pageCount)
+	assert.Equal(t, []string{"jpeg"}, ocr.formats)
+}
+
+func TestLoadPDF_ImageOnlyStrategies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.pdf")
+	require.NoError(t, os.WriteFile(path, scannedPDF(), 0644))
+	jpeg := []byte{0xff, 0xd8, 0xff, 0xd9}
+
+	tests := []struct {
+		name       string
+		strategy   OCRStrategy
+		ocr        *fakeOCR
+		wantText   string
+		wantVision bool
+		wantOCR    bool
+		wantErr    string
+	}{
+		{name: "ocr only", strategy: OCRStrategyOCROnly, ocr: &fakeOCR{text: "Rapat jam 9"}, wantText: "--- Page 1 ---\nRapat jam 9", wantOCR: true},
+		{name: "ocr only without text", strategy: OCRStrategyOCROnly, ocr: &fakeOCR{}, wantOCR: true, wantErr: "OCR found no text"},
+		{name: "vision only", strategy: OCRStrategyVisionOnly, ocr: &fakeOCR{text: "ignored"}, wantVision: true},
+		{name: "ocr then vision", strategy: OCRStrategyOCRThenVision, ocr: &fakeOCR{text: "Rapat jam 9"}, wantText: "--- Page 1 ---\nRapat jam 9", wantOCR: true},
+		{name: "ocr then vision fallback", strategy: OCRStrategyOCRThenVision, ocr: &fakeOCR{err: errors.New("boom")}, wantVision: true, wantOCR: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := newOCRTestService(tt.ocr, tt.strategy)
+
+			input, err := ps.processDocumentFile(context.Background(), path)
+			assert.Equal(t, tt.wantOCR, len(tt.ocr.formats) > 0)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantText, input.text)
+			assert.Equal(t, 1, input.pageCount)
+			if tt.wantVision {
+				assert.Equal(t, []pageImage{{data: jpeg, mimeType: "image/jpeg"}}, input.pageImages)
+			} else {
+				assert.Empty(t, input.pageImages)
+			}
+		})
+	}
+}
+
+func TestProcessJob_ScannedPDFWithVision(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.pdf")
+	require.NoError(t, os.WriteFile(path, scannedPDF(), 0644))
+
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	todoRepo, db := newTestTodoRepository(t)
+	extractor := &FakeExtractor{ImageTodos: []models.TodoItem{{Title: "Rapat jam 9"}}}
+
+	ps := NewProcessingService(extractor, todoRepo, jobService, log)
+	ps.SetOCR(&fakeOCR{}, OCRStrategyOCRThenVision)
+
+	job := newTestJob("job-1")
+	job.Type = "document"
+	job.FilePath = path
+	require.NoError(t, jobService.SubmitJob(job))
+
+	ps.ProcessJob(context.Background(), job)
+
+	stored, err := jobService.GetJob("job-1")
+	require.NoError(t, err)
+	require.Equal(t, models.JobStatusCompleted, stored.Status, stored.Error)
+	assert.Equal(t, 1, stored.Result.PageCount)
+	assert.Equal(t, 1, stored.Progress.ChunksDone)
+	require.Len(t, db.todos, 1)
+	assert.Equal(t, "Rapat jam 9", db.todos[0].Title)
+}
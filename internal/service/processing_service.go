@@ -1,193 +1,342 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"todo-agent-backend/internal/logger"
 	"todo-agent-backend/internal/models"
 	"todo-agent-backend/internal/repository"
-	"todo-agent-backend/pkg/gemini"
+	"todo-agent-backend/pkg/prompt"
+	"todo-agent-backend/pkg/retry"
 
-	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 // ProcessingService handles the core business logic for processing inputs
 type ProcessingService struct {
-	geminiClient *gemini.Client
-	todoRepo     *repository.TodoRepository
-	logger       *logger.Logger
+	extractor          TodoExtractor
+	todoRepo           *repository.TodoRepository
+	jobService         JobServiceInterface
+	documentExtractors map[string]documentExtractor
+	ocr                OCREngine
+	ocrStrategy        OCRStrategy
+	prompts            *prompt.Registry
+	objectStore        ObjectStore
+	cache              ResultCache
+	chunkSize          int
+	chunkOverlap       int
+	chunkConcurrency   int
+	committing         sync.Map // IDs of preview jobs being committed
+	logger             *logger.Logger
 }
 
 // NewProcessingService creates a new processing service
-func NewProcessingService(geminiClient *gemini.Client, todoRepo *repository.TodoRepository, logger *logger.Logger) *ProcessingService {
-	return &ProcessingService{
-		geminiClient: geminiClient,
-		todoRepo:     todoRepo,
-		logger:       logger,
+func NewProcessingService(extractor TodoExtractor, todoRepo *repository.TodoRepository, jobService JobServiceInterface, logger *logger.Logger) *ProcessingService {
+	ps := &ProcessingService{
+		extractor:   extractor,
+		todoRepo:    todoRepo,
+		jobService:  jobService,
+		ocrStrategy: OCRStrategyVisionOnly,
+		prompts:     prompt.DefaultRegistry(),
+		logger:      logger,
 	}
+	ps.documentExtractors = ps.defaultDocumentExtractors()
+
+	return ps
+}
+
+// SetPrompts replaces the built-in prompt template with the given set of templates
+func (ps *ProcessingService) SetPrompts(registry *prompt.Registry) {
+	ps.prompts = registry
 }
 
-// ProcessJob processes a job asynchronously
-func (ps *ProcessingService) ProcessJob(job *models.Job) {
+// ProcessJob processes a job asynchronously.
+// The job is treated as read-only; every status change goes through the job service.
+// When ctx ends before the job finishes, the job is left in processing for
+// the caller to fail with the reason it cancelled ctx.
+func (ps *ProcessingService) ProcessJob(ctx context.Context, job *models.Job) {
 	ps.logger.Info("Starting job processing", zap.String("job_id", job.ID))
 
 	// Update job status to processing
-	job.Status = models.JobStatusProcessing
-	job.UpdatedAt = time.Now()
+	if err := ps.jobService.UpdateJob(job.ID, models.JobStatusProcessing, nil, ""); err != nil {
+		ps.logger.Warn("Skipping job that cannot be processed",
+			zap.String("job_id", job.ID),
+			zap.Error(err))
+		return
+	}
+
+	// The upload is not needed once the job reaches a terminal state,
+	// whether it succeeds or fails
+	if job.FilePath != "" {
+		defer ps.cleanupFile(job.FilePath)
+	}
+
+	// Count outbound requests made for this job, including retries,
+	// and the rounds spent asking the model to fix invalid output
+	progress := ps.newJobProgress(job.ID)
+	extractor := ps.extractor.WithRetryCounter(progress.attempts).WithRepairCounter(progress.repairs)
+	todoRepo := ps.todoRepo.WithRetryCounter(progress.attempts)
+	defer progress.record()
 
-	// Extract text content based on job type
-	text, err := ps.extractText(job)
+	// Read input content based on job type
+	input, err := ps.readInput(ctx, job)
 	if err != nil {
-		ps.logger.Error("Failed to extract text", 
+		ps.logger.Error("Failed to read input",
 			zap.String("job_id", job.ID), 
 			zap.Error(err))
-		ps.markJobFailed(job, fmt.Sprintf("Failed to extract text: %v", err))
+		ps.markJobFailed(ctx, job, fmt.Sprintf("Failed to read input: %v", err))
+		return
+	}
+
+	// Render instructions in the language the job asked for
+	tmpl, err := ps.prompts.Get(job.Language)
+	if err != nil {
+		ps.markJobFailed(ctx, job, err.Error())
 		return
 	}
+	extractor = extractor.WithPrompt(prompt.Prompt{
+		Template: tmpl,
+		Timezone: job.Timezone,
+		Now:      referenceTime(job),
+	})
 
-	// Process with Gemini AI
-	todos, err := ps.geminiClient.ExtractTodos(text)
+	// Process with the language model, unless the same input was seen before
+	key := cacheKey(job, input, tmpl, extractor.Model())
+	todos, cacheHit, err := ps.extractCached(ctx, extractor, input, progress, key)
 	if err != nil {
-		ps.logger.Error("Failed to extract todos with Gemini", 
+		ps.logger.Error("Failed to extract todos",
 			zap.String("job_id", job.ID), 
 			zap.Error(err))
-		ps.markJobFailed(job, fmt.Sprintf("Failed to process with AI: %v", err))
+		ps.markJobFailed(ctx, job, fmt.Sprintf("Failed to process with AI: %v", err))
 		return
 	}
 
+	if todos == nil {
+		todos = []models.TodoItem{}
+	}
+	locateSources(input.text, todos)
+
 	// Convert to processing result
 	result := &models.ProcessingResult{
-		Todos:       make([]models.TodoItem, len(todos)),
-		ProcessedAt: time.Now(),
+		Todos:          todos,
+		PageCount:      input.pageCount,
+		PromptTemplate: tmpl.Name,
+		PromptVersion:  tmpl.Version,
+		SourceURL:      ps.storeSource(ctx, job),
+		CacheHit:       cacheHit,
+		ProcessedAt:    time.Now(),
 	}
 
-	for i, todo := range todos {
-		result.Todos[i] = models.TodoItem{
-			Title:       todo.Title,
-			Description: todo.Description,
-			DueDate:     todo.DueDate,
+	// A preview keeps the todos on the job until the user commits them
+	if job.Mode == models.JobModePreview {
+		result.Preview = true
+		if err := ps.jobService.UpdateJob(job.ID, models.JobStatusCompleted, result, ""); err != nil {
+			ps.logger.Error("Failed to mark job as completed",
+				zap.String("job_id", job.ID),
+				zap.Error(err))
+			return
 		}
+
+		ps.logger.Info("Job preview ready",
+			zap.String("job_id", job.ID),
+			zap.Int("todos_count", len(result.Todos)))
+		return
+	}
+
+	// Handle todos the user already has
+	if err := ps.dedupTodos(ctx, todoRepo, job, result); err != nil {
+		ps.logger.Error("Failed to deduplicate todos",
+			zap.String("job_id", job.ID),
+			zap.Error(err))
+		ps.markJobFailed(ctx, job, fmt.Sprintf("Failed to check for duplicate todos: %v", err))
+		return
 	}
 
 	// Save todos to database
-	err = ps.saveTodosToDatabase(job.UserID, result.Todos, job.Type)
+	err = ps.saveTodosToDatabase(ctx, todoRepo, job, result)
 	if err != nil {
 		ps.logger.Error("Failed to save todos to database", 
 			zap.String("job_id", job.ID), 
 			zap.Error(err))
-		ps.markJobFailed(job, fmt.Sprintf("Failed to save todos: %v", err))
+		ps.markJobFailed(ctx, job, fmt.Sprintf("Failed to save todos: %v", err))
 		return
 	}
 
 	// Update job with result
-	job.Status = models.JobStatusCompleted
-	job.Result = result
-	job.UpdatedAt = time.Now()
-
-	// Clean up temporary files
-	if job.FilePath != "" {
-		ps.cleanupFile(job.FilePath)
+	if err := ps.jobService.UpdateJob(job.ID, models.JobStatusCompleted, result, ""); err != nil {
+		ps.logger.Error("Failed to mark job as completed",
+			zap.String("job_id", job.ID),
+			zap.Error(err))
+		return
 	}
 
 	ps.logger.Info("Job processing completed", 
 		zap.String("job_id", job.ID),
-		zap.Int("todos_count", len(result.Todos)))
+		zap.Int("todos_count", len(result.Todos)),
+		zap.Int("duplicates_count", len(result.Duplicates)))
+}
+
+// jobInput is the content sent to the model for a job
+type jobInput struct {
+	text       string
+	image      []byte
+	mimeType   string
+	pageImages []pageImage // scanned PDF pages for the vision model
+	pageCount  int
 }
 
-// extractText extracts text content based on job type
-func (ps *ProcessingService) extractText(job *models.Job) (string, error) {
+// pageImage is an image found on a PDF page without text
+type pageImage struct {
+	data     []byte
+	mimeType string
+}
+
+// readInput loads the content of a job based on its type
+func (ps *ProcessingService) readInput(ctx context.Context, job *models.Job) (*jobInput, error) {
 	switch job.Type {
 	case "text":
-		return job.Content, nil
+		return &jobInput{text: job.Content}, nil
 		
 	case "image":
-		// For images, we'd typically use OCR here
-		// For now, we'll pass the file path to Gemini Vision API
-		return ps.processImageFile(job.FilePath)
+		image, mimeType, err := loadImage(job.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		return ps.recognizeImage(ctx, image, mimeType)
 		
 	case "document":
-		// For documents, we'd parse PDF/DOC files here
-		return ps.processDocumentFile(job.FilePath)
+		return ps.processDocumentFile(ctx, job.FilePath)
 		
 	default:
-		return "", fmt.Errorf("unsupported job type: %s", job.Type)
+		return nil, fmt.Errorf("unsupported job type: %s", job.Type)
 	}
 }
 
-// processImageFile processes image files (placeholder for OCR/Vision API)
-func (ps *ProcessingService) processImageFile(filePath string) (string, error) {
-	// TODO: Implement image processing with Gemini Vision API
-	// For now, return a placeholder
-	return fmt.Sprintf("Image content from file: %s", filePath), nil
+// extractTodos sends the input to the model, splitting long text into
+// chunks and sending scanned pages one at a time
+func (ps *ProcessingService) extractTodos(ctx context.Context, extractor TodoExtractor, input *jobInput, progress *jobProgress) ([]models.TodoItem, error) {
+	if input.image != nil {
+		return extractor.ExtractTodosFromImage(ctx, input.image, input.mimeType)
+	}
+	if len(input.pageImages) > 0 {
+		return ps.extractPageImages(ctx, extractor, input.pageImages, progress)
+	}
+	return ps.extractChunked(ctx, extractor, input.text, progress)
 }
 
-// processDocumentFile processes document files
-func (ps *ProcessingService) processDocumentFile(filePath string) (string, error) {
-	// Simple text file reading for now
-	// TODO: Implement proper PDF/DOC parsing
-	if strings.HasSuffix(strings.ToLower(filePath), ".txt") {
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			return "", fmt.Errorf("failed to read text file: %w", err)
-		}
-		return string(content), nil
+// processDocumentFile extracts the text of a document with the extractor registered for its extension
+func (ps *ProcessingService) processDocumentFile(ctx context.Context, filePath string) (*jobInput, error) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+
+	extract, ok := ps.documentExtractors[ext]
+	if !ok {
+		return nil, fmt.Errorf("unsupported document format: %s", ext)
 	}
 	
-	// For other document types, return placeholder
-	return fmt.Sprintf("Document content from file: %s", filePath), nil
+	return extract(ctx, filePath)
 }
 
 // saveTodosToDatabase saves extracted todos to the database
-func (ps *ProcessingService) saveTodosToDatabase(userID string, todoItems []models.TodoItem, sourceType string) error {
-	if len(todoItems) == 0 {
+func (ps *ProcessingService) saveTodosToDatabase(ctx context.Context, todoRepo *repository.TodoRepository, job *models.Job, result *models.ProcessingResult) error {
+	if len(result.Todos) == 0 {
 		return nil
 	}
 
-	todos := make([]models.Todo, len(todoItems))
-	now := time.Now()
+	// Save to database
+	return todoRepo.InsertTodos(ctx, BuildTodos(job, result, time.Now()))
+}
 
-	for i, item := range todoItems {
-		todo := models.Todo{
-			ID:          uuid.New(),
-			UserID:      userID,
-			Title:       item.Title,
-			SourceType:  sourceType,
-			CreatedAt:   now,
-		}
+// referenceTime is the moment relative dates in the input are resolved against
+func referenceTime(job *models.Job) time.Time {
+	if job.ReferenceTime != nil {
+		return *job.ReferenceTime
+	}
+	return job.CreatedAt
+}
 
-		// Set description if provided
-		if item.Description != "" {
-			todo.Description = &item.Description
-		}
+// jobProgress collects the counters of one job and stores them on the job
+type jobProgress struct {
+	ps       *ProcessingService
+	jobID    string
+	attempts *retry.Counter // outbound requests including retries
+	repairs  *retry.Counter // rounds spent asking the model to fix invalid output
 
-		// Parse due date if provided
-		if item.DueDate != nil && *item.DueDate != "" {
-			if dueDate, err := time.Parse("2006-01-02", *item.DueDate); err == nil {
-				todo.DueDate = &dueDate
-			}
-		}
+	mu          sync.Mutex
+	chunksTotal int
+	chunksDone  int
+}
 
-		todos[i] = todo
+func (ps *ProcessingService) newJobProgress(jobID string) *jobProgress {
+	return &jobProgress{
+		ps:       ps,
+		jobID:    jobID,
+		attempts: &retry.Counter{},
+		repairs:  &retry.Counter{},
 	}
+}
 
-	// Save to database
-	return ps.todoRepo.InsertTodos(todos)
+// setChunks records how many chunks the input was split into
+func (p *jobProgress) setChunks(total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.chunksTotal = total
+	p.store()
+}
+
+// chunkDone records a chunk that finished extraction
+func (p *jobProgress) chunkDone() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.chunksDone++
+	p.store()
+}
+
+// record stores the current counters on the job
+func (p *jobProgress) record() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.store()
+}
+
+// store writes a snapshot of the counters. The caller holds mu so
+// snapshots reach the job in the order they were taken.
+func (p *jobProgress) store() {
+	progress := models.JobProgress{
+		RequestAttempts: p.attempts.Attempts(),
+		RepairRounds:    p.repairs.Attempts(),
+		ChunksTotal:     p.chunksTotal,
+		ChunksDone:      p.chunksDone,
+	}
+
+	if err := p.ps.jobService.UpdateProgress(p.jobID, progress); err != nil {
+		p.ps.logger.Warn("Failed to record job progress",
+			zap.String("job_id", p.jobID),
+			zap.Error(err))
+	}
 }
 
 // markJobFailed marks a job as failed with error message
-func (ps *ProcessingService) markJobFailed(job *models.Job, errorMsg string) {
-	job.Status = models.JobStatusFailed
-	job.Error = errorMsg
-	job.UpdatedAt = time.Now()
+func (ps *ProcessingService) markJobFailed(ctx context.Context, job *models.Job, errorMsg string) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err := ps.jobService.UpdateJob(job.ID, models.JobStatusFailed, nil, errorMsg); err != nil {
+		ps.logger.Error("Failed to mark job as failed",
+			zap.String("job_id", job.ID),
+			zap.Error(err))
+	}
 }
 
 // cleanupFile removes temporary files
 func (ps *ProcessingService) cleanupFile(filePath string) {
-	if err := os.Remove(filePath); err != nil {
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
 		ps.logger.Warn("Failed to cleanup file", 
 			zap.String("file_path", filePath), 
 			zap.Error(err))
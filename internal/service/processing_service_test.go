@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+	"todo-agent-backend/pkg/prompt"
+	"todo-agent-backend/pkg/retry"
+	"todo-agent-backend/pkg/supabase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSupabase accepts todo inserts and keeps the inserted rows, ignoring
+// rows whose ID it already has like resolution=ignore-duplicates. Reads
+// return existing and updates are recorded in patches and counted in updates.
+type fakeSupabase struct {
+	mu       sync.Mutex
+	todos    []models.Todo
+	existing []models.Todo
+	patches  map[string]models.TodoPatch
+	updates  int
+}
+
+func newTestTodoRepository(t *testing.T) (*repository.TodoRepository, *fakeSupabase) {
+	fake := &fakeSupabase{patches: make(map[string]models.TodoPatch)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			require.NoError(t, json.NewEncoder(w).Encode(fake.existing))
+
+		case http.MethodPatch:
+			var patch models.TodoPatch
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&patch))
+			fake.patches[strings.TrimPrefix(r.URL.Query().Get("id"), "eq.")] = patch
+			fake.updates++
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			var todos []models.Todo
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&todos))
+			for _, todo := range todos {
+				if !slices.ContainsFunc(fake.todos, func(saved models.Todo) bool { return saved.ID == todo.ID }) {
+					fake.todos = append(fake.todos, todo)
+				}
+			}
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := supabase.NewClient(server.URL, "test-key", time.Second, retry.NewPolicy(0))
+	return repository.NewTodoRepository(client), fake
+}
+
+func TestProcessJob_ExtractsAndSavesTodos(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	todoRepo, db := newTestTodoRepository(t)
+
+	ps := NewProcessingService(&FakeExtractor{}, todoRepo, jobService, log)
+
+	job := newTestJob("job-1")
+	job.Content = "- Kirim laporan 2025-07-18\n\n- Beli kopi"
+	require.NoError(t, jobService.SubmitJob(job))
+
+	ps.ProcessJob(context.Background(), job)
+
+	stored, err := jobService.GetJob("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCompleted, stored.Status)
+	require.Len(t, stored.Result.Todos, 2)
+	assert.Equal(t, "Kirim laporan 2025-07-18", stored.Result.Todos[0].Title)
+	assert.Equal(t, "2025-07-18", *stored.Result.Todos[0].DueDate)
+	assert.Equal(t, "Beli kopi", stored.Result.Todos[1].Title)
+
+	assert.Equal(t, "builtin-id", stored.Result.PromptTemplate)
+	assert.Equal(t, "5", stored.Result.PromptVersion)
+
+	// One model call and one insert
+	assert.Equal(t, 2, stored.Progress.RequestAttempts)
+
+	require.Len(t, db.todos, 2)
+	assert.Equal(t, "test-user", db.todos[0].UserID)
+	assert.Equal(t, "text", db.todos[0].SourceType)
+	require.NotNil(t, db.todos[0].DueDate)
+	assert.Equal(t, "2025-07-18", db.todos[0].DueDate.Format("2006-01-02"))
+}
+
+func TestProcessJob_UsesRequestedLanguage(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	todoRepo, _ := newTestTodoRepository(t)
+
+	en, err := prompt.Parse(prompt.Meta{Name: "todo-en", Version: "3", Language: "en"},
+		`{{define "text"}}{{.Text}}{{end}}{{define "image"}}{{end}}{{define "repair"}}{{end}}`)
+	require.NoError(t, err)
+	registry, err := prompt.NewRegistry("id", prompt.Default(), en)
+	require.NoError(t, err)
+
+	ps := NewProcessingService(&FakeExtractor{}, todoRepo, jobService, log)
+	ps.SetPrompts(registry)
+
+	job := newTestJob("job-1")
+	job.Content = "Send report"
+	job.Language = "en"
+	require.NoError(t, jobService.SubmitJob(job))
+
+	ps.ProcessJob(context.Background(), job)
+
+	stored, err := jobService.GetJob("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCompleted, stored.Status)
+	assert.Equal(t, "todo-en", stored.Result.PromptTemplate)
+	assert.Equal(t, "3", stored.Result.PromptVersion)
+}
+
+func TestProcessJob_StoresDueDatesInUserTimezone(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	todoRepo, db := newTestTodoRepository(t)
+
+	ps := NewProcessingService(&FakeExtractor{}, todoRepo, jobService, log)
+
+	tests := []struct {
+		timezone string
+		want     time.Time
+	}{
+		{timezone: "Asia/Jakarta", want: time.Date(2025, 7, 17, 17, 0, 0, 0, time.UTC)},  // WIB
+		{timezone: "Asia/Makassar", want: time.Date(2025, 7, 17, 16, 0, 0, 0, time.UTC)}, // WITA
+		{timezone: "Asia/Jayapura", want: time.Date(2025, 7, 17, 15, 0, 0, 0, time.UTC)}, // WIT
+	}
+
+	for _, tt := range tests {
+		db.todos = nil
+
+		job := newTestJob("job-" + tt.timezone)
+		job.Content = "Kirim laporan 2025-07-18"
+		job.Timezone = tt.timezone
+		require.NoError(t, jobService.SubmitJob(job))
+
+		ps.ProcessJob(context.Background(), job)
+
+		require.Len(t, db.todos, 1, tt.timezone)
+		require.NotNil(t, db.todos[0].DueDate)
+		assert.True(t, tt.want.Equal(*db.todos[0].DueDate), "%s: got %s", tt.timezone, db.todos[0].DueDate)
+	}
+}
+
+func TestProcessJob_ExtractorFailure(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	todoRepo, db := newTestTodoRepository(t)
+
+	ps := NewProcessingService(&FakeExtractor{Err: errors.New("quota exceeded")}, todoRepo, jobService, log)
+
+	job := newTestJob("job-1")
+	require.NoError(t, jobService.SubmitJob(job))
+
+	ps.ProcessJob(context.Background(), job)
+
+	stored, err := jobService.GetJob("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusFailed, stored.Status)
+	assert.Equal(t, "Failed to process with AI: quota exceeded", stored.Error)
+	assert.Empty(t, db.todos)
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"todo-agent-backend/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// pageMarker matches the page headers pdf.Document.Text puts in front of every page
+var pageMarker = regexp.MustCompile(`(?m)^--- Page (\d+) ---$`)
+
+// unsafeKeyChars are replaced in user IDs before they become part of an object key
+var unsafeKeyChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// SetObjectStore keeps a copy of every job's input in store and links
+// the todos created from it with source_url
+func (ps *ProcessingService) SetObjectStore(store ObjectStore) {
+	ps.objectStore = store
+}
+
+// storeSource uploads the original input of a job and returns its URL.
+// Provenance is best effort: a failed upload is logged and the todos are
+// saved without a source URL.
+func (ps *ProcessingService) storeSource(ctx context.Context, job *models.Job) string {
+	if ps.objectStore == nil {
+		return ""
+	}
+
+	data, ext, err := sourceData(job)
+	if err != nil {
+		ps.logger.Warn("Failed to read job input for storage",
+			zap.String("job_id", job.ID),
+			zap.Error(err))
+		return ""
+	}
+
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	key := fmt.Sprintf("%s/%s%s", unsafeKeyChars.ReplaceAllString(job.UserID, "_"), job.ID, ext)
+	url, err := ps.objectStore.Put(ctx, key, data, contentType)
+	if err != nil {
+		ps.logger.Warn("Failed to store job input",
+			zap.String("job_id", job.ID),
+			zap.Error(err))
+		return ""
+	}
+
+	return url
+}
+
+// sourceData returns the original input of a job and its file extension
+func sourceData(job *models.Job) ([]byte, string, error) {
+	if job.FilePath == "" {
+		return []byte(job.Content), ".txt", nil
+	}
+
+	data, err := os.ReadFile(job.FilePath)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, strings.ToLower(filepath.Ext(job.FilePath)), nil
+}
+
+// locateSources finds the excerpt of every todo and subtask in the input
+// text, filling in its character offsets and PDF page
+func locateSources(text string, items []models.TodoItem) {
+	pages := pageMarker.FindAllStringSubmatchIndex(text, -1)
+
+	var locate func(items []models.TodoItem)
+	locate = func(items []models.TodoItem) {
+		for i := range items {
+			if source := items[i].Source; source != nil && text != "" {
+				if start := indexFold(text, source.Text); start >= 0 {
+					runeStart := utf8.RuneCountInString(text[:start])
+					runeEnd := runeStart + utf8.RuneCountInString(source.Text)
+					source.Start = &runeStart
+					source.End = &runeEnd
+					source.Page = pageAt(text, pages, start)
+				}
+			}
+			locate(items[i].Subtasks)
+		}
+	}
+	locate(items)
+}
+
+// indexFold returns the byte offset of the first match of substr in s,
+// ignoring case when there is no exact match
+func indexFold(s, substr string) int {
+	if idx := strings.Index(s, substr); idx >= 0 {
+		return idx
+	}
+
+	// Only fall back when lowercasing keeps byte offsets intact
+	lower, lowerSub := strings.ToLower(s), strings.ToLower(substr)
+	if len(lower) != len(s) || len(lowerSub) != len(substr) {
+		return -1
+	}
+	return strings.Index(lower, lowerSub)
+}
+
+// pageAt returns the number of the page containing byte offset pos, or 0
+// when the text has no page markers before it
+func pageAt(text string, pages [][]int, pos int) int {
+	page := 0
+	for _, m := range pages {
+		if m[0] > pos {
+			break
+		}
+		page, _ = strconv.Atoi(text[m[2]:m[3]])
+	}
+	return page
+}
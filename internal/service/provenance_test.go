@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+	"todo-agent-backend/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocateSources(t *testing.T) {
+	text := "--- Page 1 ---\nRapat ké-2 dengan klien\n\n--- Page 2 ---\nKirim laporan\n- Pesan ruangan"
+
+	items := []models.TodoItem{
+		{
+			Title:  "Send report",
+			Source: &models.SourceSpan{Text: "kirim laporan"},
+			Subtasks: []models.TodoItem{
+				{Title: "Book room", Source: &models.SourceSpan{Text: "Pesan ruangan"}},
+			},
+		},
+		{Title: "Meeting", Source: &models.SourceSpan{Text: "dengan klien"}},
+		{Title: "Invented", Source: &models.SourceSpan{Text: "tidak ada di teks"}},
+		{Title: "No excerpt"},
+	}
+
+	locateSources(text, items)
+
+	// Case-insensitive match on page 2
+	report := items[0].Source
+	assert.Equal(t, 2, report.Page)
+	require.NotNil(t, report.Start)
+	assert.Equal(t, "Kirim laporan", string([]rune(text)[*report.Start:*report.End]))
+
+	room := items[0].Subtasks[0].Source
+	assert.Equal(t, 2, room.Page)
+	assert.Equal(t, "Pesan ruangan", string([]rune(text)[*room.Start:*room.End]))
+
+	// Offsets count characters, not bytes
+	meeting := items[1].Source
+	assert.Equal(t, 1, meeting.Page)
+	assert.Equal(t, "dengan klien", string([]rune(text)[*meeting.Start:*meeting.End]))
+
+	// Excerpts that are not in the text keep only their text
+	assert.Nil(t, items[2].Source.Start)
+	assert.Zero(t, items[2].Source.Page)
+	assert.Nil(t, items[3].Source)
+}
+
+func TestProcessJob_StoresSource(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	todoRepo, db := newTestTodoRepository(t)
+
+	objects := t.TempDir()
+	store, err := storage.NewLocal(objects, "https://files.example.com")
+	require.NoError(t, err)
+
+	ps := NewProcessingService(&FakeExtractor{}, todoRepo, jobService, log)
+	ps.SetObjectStore(store)
+
+	job := newTestJob("job-1")
+	job.UserID = "user/1"
+	job.Content = "Kirim laporan"
+	require.NoError(t, jobService.SubmitJob(job))
+
+	ps.ProcessJob(context.Background(), job)
+
+	stored, err := jobService.GetJob("job-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCompleted, stored.Status)
+	assert.Equal(t, "https://files.example.com/user_1/job-1.txt", stored.Result.SourceURL)
+
+	data, err := os.ReadFile(filepath.Join(objects, "user_1", "job-1.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "Kirim laporan", string(data))
+
+	require.Len(t, db.todos, 1)
+	require.NotNil(t, db.todos[0].SourceURL)
+	assert.Equal(t, "https://files.example.com/user_1/job-1.txt", *db.todos[0].SourceURL)
+	require.NotNil(t, db.todos[0].SourceSpan)
+	assert.Equal(t, "Kirim laporan", db.todos[0].SourceSpan.Text)
+	assert.Equal(t, 0, *db.todos[0].SourceSpan.Start)
+	assert.Equal(t, 13, *db.todos[0].SourceSpan.End)
+}
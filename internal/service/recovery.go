@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"todo-agent-backend/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// Recover re-queues jobs that a previous run left pending or processing.
+// Jobs whose input is gone or that already used maxAttempts attempts are
+// marked as failed instead. Only jobs created before Start are considered,
+// so it is safe to call while new jobs are being submitted.
+func (wp *WorkerPool) Recover(ctx context.Context, maxAttempts int) error {
+	jobs, err := wp.jobService.ListJobsByStatus(models.JobStatusPending, models.JobStatusProcessing)
+	if err != nil {
+		return fmt.Errorf("failed to list unfinished jobs: %w", err)
+	}
+
+	requeued, failed := 0, 0
+	for _, job := range jobs {
+		if !job.CreatedAt.Before(wp.startedAt) {
+			continue
+		}
+
+		if reason := unrecoverableReason(job, maxAttempts); reason != "" {
+			wp.abandonJob(job, "interrupted by restart: "+reason)
+			failed++
+			continue
+		}
+
+		if job.Status != models.JobStatusPending {
+			if err := wp.jobService.UpdateJob(job.ID, models.JobStatusPending, nil, ""); err != nil {
+				wp.logger.Error("Failed to reset interrupted job",
+					zap.String("job_id", job.ID),
+					zap.Error(err))
+				continue
+			}
+		}
+
+		if err := wp.enqueueWait(ctx, job); err != nil {
+			// Jobs that could not be queued stay pending for the next start
+			wp.logger.Warn("Stopped recovering jobs", zap.Error(err))
+			break
+		}
+		requeued++
+	}
+
+	wp.logger.Info("Recovered unfinished jobs",
+		zap.Int("requeued_count", requeued),
+		zap.Int("failed_count", failed),
+	)
+
+	return nil
+}
+
+// enqueueWait adds a job to the queue, waiting for a free slot until ctx
+// ends or the pool starts shutting down
+func (wp *WorkerPool) enqueueWait(ctx context.Context, job *models.Job) error {
+	wp.mutex.RLock()
+	defer wp.mutex.RUnlock()
+
+	if wp.closed {
+		return ErrShuttingDown
+	}
+
+	select {
+	case wp.queue <- job:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-wp.closing:
+		return ErrShuttingDown
+	}
+}
+
+// unrecoverableReason explains why a job cannot be resumed, or returns "" if it can
+func unrecoverableReason(job *models.Job, maxAttempts int) string {
+	if maxAttempts > 0 && job.Attempts >= maxAttempts {
+		return fmt.Sprintf("giving up after %d attempts", job.Attempts)
+	}
+
+	switch job.Type {
+	case "text":
+		if job.Content == "" {
+			return "input text is no longer available"
+		}
+	default:
+		if job.FilePath == "" {
+			return "uploaded file is no longer available"
+		}
+		if _, err := os.Stat(job.FilePath); err != nil {
+			return "uploaded file is no longer available"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPool_Recover(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	processor := newBlockingProcessor()
+	defer close(processor.release)
+
+	before := time.Now().Add(-time.Minute)
+	jobs := []*models.Job{
+		{ID: "interrupted", Type: "text", Content: "Send report", Status: models.JobStatusProcessing, Attempts: 1},
+		{ID: "queued", Type: "text", Content: "Call Budi", Status: models.JobStatusPending},
+		{ID: "missing-file", Type: "image", FilePath: "/nonexistent/notes.jpg", Status: models.JobStatusPending},
+		{ID: "exhausted", Type: "text", Content: "Book room", Status: models.JobStatusProcessing, Attempts: 3},
+		{ID: "done", Type: "text", Content: "Pay invoice", Status: models.JobStatusCompleted},
+	}
+	for _, job := range jobs {
+		job.CreatedAt = before
+		require.NoError(t, jobService.SubmitJob(job))
+	}
+
+	pool := NewWorkerPool(processor, jobService, log, 1, 10, time.Minute)
+	pool.Start()
+
+	require.NoError(t, pool.Recover(context.Background(), 3))
+
+	// The first requeued job is running, the other waits in the queue
+	<-processor.started
+	assert.Len(t, pool.queue, 1)
+
+	for id, want := range map[string]models.JobStatusEnum{
+		"missing-file": models.JobStatusFailed,
+		"exhausted":    models.JobStatusFailed,
+		"done":         models.JobStatusCompleted,
+	} {
+		job, err := jobService.GetJob(id)
+		require.NoError(t, err)
+		assert.Equal(t, want, job.Status, id)
+	}
+
+	job, err := jobService.GetJob("exhausted")
+	require.NoError(t, err)
+	assert.Contains(t, job.Error, "interrupted by restart")
+}
+
+func TestWorkerPool_ShutdownStopsRecovery(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	jobService := NewJobService(repository.NewMemoryJobStore(), log)
+	processor := newBlockingProcessor()
+
+	before := time.Now().Add(-time.Minute)
+	for _, id := range []string{"job-1", "job-2", "job-3"} {
+		job := newTestJob(id)
+		job.CreatedAt = before
+		require.NoError(t, jobService.SubmitJob(job))
+	}
+
+	pool := NewWorkerPool(processor, jobService, log, 1, 1, time.Minute)
+	pool.Start()
+
+	// One job runs and one fills the queue, so recovery waits for a slot
+	recovered := make(chan error, 1)
+	go func() { recovered <- pool.Recover(context.Background(), 3) }()
+	<-processor.started
+	require.Eventually(t, func() bool { return len(pool.queue) == 1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		shutdown <- pool.Shutdown(ctx)
+	}()
+
+	select {
+	case err := <-shutdown:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown blocked behind recovery")
+	}
+	assert.NoError(t, <-recovered)
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// BuildTodos converts the items of a result into todos owned by the job's
+// user. Subtasks are flattened after their parent and point to it with
+// ParentID, so parents are always inserted first. Items merged into an
+// existing todo point to it instead. Date-only due dates are the start of
+// that day in the job's timezone. Todos of a result with a CommitID get
+// IDs derived from it, so building the result again yields the same IDs.
+func BuildTodos(job *models.Job, result *models.ProcessingResult, createdAt time.Time) []models.Todo {
+	b := todoBuilder{
+		job:       job,
+		loc:       utils.LoadLocation(job.Timezone),
+		createdAt: createdAt,
+		idSpace:   result.CommitID,
+	}
+	if result.SourceURL != "" {
+		b.sourceURL = &result.SourceURL
+	}
+
+	return b.append(make([]models.Todo, 0, len(result.Todos)), result.Todos, nil)
+}
+
+// todoBuilder holds the values shared by every todo of a job
+type todoBuilder struct {
+	job       *models.Job
+	loc       *time.Location
+	sourceURL *string
+	createdAt time.Time
+	idSpace   *uuid.UUID // namespace of derived todo IDs, random IDs when nil
+	built     int
+}
+
+// newID returns the ID of the next todo
+func (b *todoBuilder) newID() uuid.UUID {
+	if b.idSpace == nil {
+		return uuid.New()
+	}
+	b.built++
+	return uuid.NewSHA1(*b.idSpace, []byte(strconv.Itoa(b.built)))
+}
+
+func (b *todoBuilder) append(todos []models.Todo, items []models.TodoItem, parentID *uuid.UUID) []models.Todo {
+	for _, item := range items {
+		item := item // the todo keeps pointers into item
+
+		parent := parentID
+		if parent == nil {
+			parent = item.ParentID
+		}
+
+		todo := models.Todo{
+			ID:               b.newID(),
+			UserID:           b.job.UserID,
+			Title:            item.Title,
+			DueDate:          utils.ParseDueDate(item.DueDate, item.DueTime, b.loc),
+			Tags:             item.Tags,
+			EstimatedMinutes: item.EstimatedMinutes,
+			ParentID:         parent,
+			DuplicateOf:      item.DuplicateOf,
+			SourceType:       b.job.Type,
+			SourceURL:        b.sourceURL,
+			SourceSpan:       item.Source,
+			CreatedAt:        b.createdAt,
+		}
+
+		if item.Description != "" {
+			todo.Description = &item.Description
+		}
+		if item.Priority != "" {
+			todo.Priority = &item.Priority
+		}
+		if item.Assignee != "" {
+			todo.Assignee = &item.Assignee
+		}
+		if todo.Tags == nil {
+			todo.Tags = []string{}
+		}
+
+		todos = append(todos, todo)
+		todos = b.append(todos, item.Subtasks, &todo.ID)
+	}
+
+	return todos
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"todo-agent-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTodos(t *testing.T) {
+	job := &models.Job{UserID: "user-1", Type: "text", Timezone: "Asia/Jakarta"}
+	minutes := 30
+	dueDate := "2025-07-18"
+	createdAt := time.Now()
+
+	todos := BuildTodos(job, &models.ProcessingResult{Todos: []models.TodoItem{
+		{
+			Title:            "Kirim laporan",
+			DueDate:          &dueDate,
+			Priority:         models.PriorityHigh,
+			Tags:             []string{"finance"},
+			EstimatedMinutes: &minutes,
+			Assignee:         "Budi",
+		},
+		{Title: "Beli kopi"},
+	}}, createdAt)
+
+	require.Len(t, todos, 2)
+	assert.Equal(t, "user-1", todos[0].UserID)
+	assert.Equal(t, "text", todos[0].SourceType)
+	assert.Equal(t, createdAt, todos[0].CreatedAt)
+	require.NotNil(t, todos[0].DueDate)
+	assert.True(t, time.Date(2025, 7, 17, 17, 0, 0, 0, time.UTC).Equal(*todos[0].DueDate))
+	assert.Equal(t, "high", *todos[0].Priority)
+	assert.Equal(t, []string{"finance"}, todos[0].Tags)
+	assert.Equal(t, 30, *todos[0].EstimatedMinutes)
+	assert.Equal(t, "Budi", *todos[0].Assignee)
+
+	// Missing details stay null, tags become an empty list
+	assert.Nil(t, todos[1].Description)
+	assert.Nil(t, todos[1].Priority)
+	assert.Nil(t, todos[1].Assignee)
+	assert.Nil(t, todos[1].EstimatedMinutes)
+	assert.Equal(t, []string{}, todos[1].Tags)
+	assert.NotEqual(t, todos[0].ID, todos[1].ID)
+}
+
+func TestBuildTodos_Subtasks(t *testing.T) {
+	job := &models.Job{UserID: "user-1", Type: "document"}
+
+	todos := BuildTodos(job, &models.ProcessingResult{Todos: []models.TodoItem{
+		{
+			Title: "Siapkan rapat",
+			Subtasks: []models.TodoItem{
+				{Title: "Pesan ruangan"},
+				{Title: "Kirim undangan", Subtasks: []models.TodoItem{{Title: "Tim finance"}}},
+			},
+		},
+		{Title: "Beli kopi"},
+	}}, time.Now())
+
+	// Parents come before their subtasks
+	require.Len(t, todos, 5)
+	titles := make([]string, len(todos))
+	for i, todo := range todos {
+		titles[i] = todo.Title
+	}
+	assert.Equal(t, []string{"Siapkan rapat", "Pesan ruangan", "Kirim undangan", "Tim finance", "Beli kopi"}, titles)
+
+	assert.Nil(t, todos[0].ParentID)
+	assert.Equal(t, todos[0].ID, *todos[1].ParentID)
+	assert.Equal(t, todos[0].ID, *todos[2].ParentID)
+	assert.Equal(t, todos[2].ID, *todos[3].ParentID)
+	assert.Nil(t, todos[4].ParentID)
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrQueueFull    = errors.New("job queue is full")
+	ErrShuttingDown = errors.New("server is shutting down")
+)
+
+// WorkerPool runs jobs on a fixed number of workers fed by a bounded queue
+type WorkerPool struct {
+	processor  ProcessingServiceInterface
+	jobService JobServiceInterface
+	logger     *logger.Logger
+	queue      chan *models.Job
+	maxWorkers int
+	jobTimeout time.Duration
+	wg         sync.WaitGroup
+	mutex      sync.RWMutex
+	closed     bool
+	closing    chan struct{} // closed when Shutdown starts, wakes blocked senders
+	closeOnce  sync.Once
+	ctx        context.Context
+	cancel     context.CancelFunc
+	startedAt  time.Time
+}
+
+// NewWorkerPool creates a new worker pool
+func NewWorkerPool(processor ProcessingServiceInterface, jobService JobServiceInterface, logger *logger.Logger, maxWorkers, queueSize int, jobTimeout time.Duration) *WorkerPool {
+	// ctx is cancelled when the shutdown grace period expires
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &WorkerPool{
+		processor:  processor,
+		jobService: jobService,
+		logger:     logger,
+		queue:      make(chan *models.Job, queueSize),
+		maxWorkers: maxWorkers,
+		jobTimeout: jobTimeout,
+		closing:    make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Start launches the workers
+func (wp *WorkerPool) Start() {
+	wp.startedAt = time.Now()
+
+	for i := 0; i < wp.maxWorkers; i++ {
+		wp.wg.Add(1)
+		go wp.worker(i)
+	}
+
+	wp.logger.Info("Worker pool started",
+		zap.Int("max_workers", wp.maxWorkers),
+		zap.Int("queue_size", cap(wp.queue)),
+		zap.Duration("job_timeout", wp.jobTimeout),
+	)
+}
+
+// Enqueue adds a job to the queue without blocking.
+// It returns ErrQueueFull when no queue slot is available and
+// ErrShuttingDown once Shutdown has been called.
+func (wp *WorkerPool) Enqueue(job *models.Job) error {
+	wp.mutex.RLock()
+	defer wp.mutex.RUnlock()
+
+	if wp.closed {
+		return ErrShuttingDown
+	}
+
+	select {
+	case wp.queue <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Shutdown stops accepting new jobs and waits for queued and running jobs
+// to finish. When ctx expires first, running jobs are cancelled and every
+// job that is still queued or running is marked as failed so clients
+// polling its status see a terminal state.
+func (wp *WorkerPool) Shutdown(ctx context.Context) error {
+	// Senders waiting for a queue slot hold the read lock, so release them first
+	wp.closeOnce.Do(func() { close(wp.closing) })
+
+	wp.mutex.Lock()
+	if wp.closed {
+		wp.mutex.Unlock()
+		return nil
+	}
+	wp.closed = true
+	close(wp.queue)
+	wp.mutex.Unlock()
+
+	wp.logger.Info("Draining worker pool", zap.Int("queued_jobs", len(wp.queue)))
+
+	done := make(chan struct{})
+	go func() {
+		wp.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		wp.logger.Info("Worker pool drained")
+		return nil
+	case <-ctx.Done():
+		wp.logger.Warn("Shutdown grace period expired, failing remaining jobs")
+		wp.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// worker processes jobs from the queue until it is closed
+func (wp *WorkerPool) worker(id int) {
+	defer wp.wg.Done()
+
+	for job := range wp.queue {
+		if wp.ctx.Err() != nil {
+			wp.abandonJob(job, "server shut down before the job was processed")
+			continue
+		}
+
+		wp.logger.Debug("Worker picked up job",
+			zap.Int("worker_id", id),
+			zap.String("job_id", job.ID))
+		wp.runJob(job)
+	}
+}
+
+// runJob processes a single job and enforces the job deadline. A panic
+// fails the job instead of taking down the server; a failed job is not
+// recovered on the next start, so a bad input cannot crash it again.
+func (wp *WorkerPool) runJob(job *models.Job) {
+	ctx, cancel := context.WithTimeout(wp.ctx, wp.jobTimeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			wp.logger.Error("Job panicked",
+				zap.String("job_id", job.ID),
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+			wp.abandonJob(job, "job failed unexpectedly while processing its input")
+		}
+	}()
+
+	wp.processor.ProcessJob(ctx, job)
+
+	// The processor leaves the job as it is when its context ends,
+	// so record why it was stopped
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		wp.logger.Warn("Job exceeded deadline",
+			zap.String("job_id", job.ID),
+			zap.Duration("job_timeout", wp.jobTimeout))
+		wp.failJob(job, fmt.Sprintf("job timed out after %s", wp.jobTimeout))
+	case context.Canceled:
+		wp.abandonJob(job, "job interrupted by server shutdown")
+	}
+}
+
+// failJob marks a job as failed
+func (wp *WorkerPool) failJob(job *models.Job, reason string) {
+	err := wp.jobService.UpdateJob(job.ID, models.JobStatusFailed, nil, reason)
+	if errors.Is(err, ErrInvalidTransition) {
+		// The job finished before it could be failed
+		return
+	}
+	if err != nil {
+		wp.logger.Warn("Failed to mark job as failed",
+			zap.String("job_id", job.ID),
+			zap.Error(err))
+	}
+}
+
+// abandonJob fails a job that will not be processed and removes its upload
+func (wp *WorkerPool) abandonJob(job *models.Job, reason string) {
+	wp.failJob(job, reason)
+
+	if job.FilePath != "" {
+		if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+			wp.logger.Warn("Failed to cleanup file",
+				zap.String("file_path", job.FilePath),
+				zap.Error(err))
+		}
+	}
+}
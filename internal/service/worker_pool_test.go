@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"todo-agent-backend/internal/logger"
+	"todo-agent-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingProcessor blocks every job until release is closed
+type blockingProcessor struct {
+	started chan string
+	release chan struct{}
+	mutex   sync.Mutex
+	running int
+	peak    int
+}
+
+func newBlockingProcessor() *blockingProcessor {
+	return &blockingProcessor{
+		started: make(chan string, 100),
+		release: make(chan struct{}),
+	}
+}
+
+func (p *blockingProcessor) ProcessJob(ctx context.Context, job *models.Job) {
+	p.mutex.Lock()
+	p.running++
+	if p.running > p.peak {
+		p.peak = p.running
+	}
+	p.mutex.Unlock()
+
+	p.started <- job.ID
+	select {
+	case <-p.release:
+	case <-ctx.Done():
+	}
+
+	p.mutex.Lock()
+	p.running--
+	p.mutex.Unlock()
+}
+
+// statusRecorder records the last status written for each job
+type statusRecorder struct {
+	JobServiceInterface
+	mutex    sync.Mutex
+	statuses map[string]models.JobStatusEnum
+}
+
+func newStatusRecorder() *statusRecorder {
+	return &statusRecorder{statuses: make(map[string]models.JobStatusEnum)}
+}
+
+func (r *statusRecorder) UpdateJob(jobID string, status models.JobStatusEnum, result *models.ProcessingResult, errorMsg string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.statuses[jobID] = status
+	return nil
+}
+
+func (r *statusRecorder) status(jobID string) models.JobStatusEnum {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.statuses[jobID]
+}
+
+func newTestJob(id string) *models.Job {
+	return &models.Job{
+		ID:      id,
+		UserID:  "test-user",
+		Type:    "text",
+		Content: "Send report",
+		Status:  models.JobStatusPending,
+	}
+}
+
+func TestWorkerPool_BoundsWorkersAndQueue(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	processor := newBlockingProcessor()
+
+	pool := NewWorkerPool(processor, newStatusRecorder(), log, 2, 1, time.Minute)
+	pool.Start()
+
+	// Keep both workers busy
+	require.NoError(t, pool.Enqueue(newTestJob("job-1")))
+	<-processor.started
+	require.NoError(t, pool.Enqueue(newTestJob("job-2")))
+	<-processor.started
+
+	// One job fits in the queue, the next one is rejected
+	assert.NoError(t, pool.Enqueue(newTestJob("job-3")))
+	assert.ErrorIs(t, pool.Enqueue(newTestJob("job-4")), ErrQueueFull)
+
+	close(processor.release)
+	<-processor.started
+
+	processor.mutex.Lock()
+	defer processor.mutex.Unlock()
+	assert.Equal(t, 2, processor.peak)
+}
+
+func TestWorkerPool_JobTimeout(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	recorder := newStatusRecorder()
+	processor := newBlockingProcessor()
+
+	pool := NewWorkerPool(processor, recorder, log, 1, 1, 20*time.Millisecond)
+	pool.Start()
+
+	require.NoError(t, pool.Enqueue(newTestJob("slow-job")))
+
+	<-processor.started
+	assert.Eventually(t, func() bool {
+		return recorder.status("slow-job") == models.JobStatusFailed
+	}, time.Second, 5*time.Millisecond)
+
+	close(processor.release)
+}
+
+// panickingProcessor panics on every job
+type panickingProcessor struct{}
+
+func (panickingProcessor) ProcessJob(ctx context.Context, job *models.Job) {
+	panic("slice bounds out of range")
+}
+
+func TestWorkerPool_PanicFailsJob(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	recorder := newStatusRecorder()
+
+	pool := NewWorkerPool(panickingProcessor{}, recorder, log, 1, 2, time.Minute)
+	pool.Start()
+
+	require.NoError(t, pool.Enqueue(newTestJob("job-1")))
+	require.NoError(t, pool.Enqueue(newTestJob("job-2")))
+
+	// The worker survives the first panic and processes the next job
+	require.NoError(t, pool.Shutdown(context.Background()))
+	assert.Equal(t, models.JobStatusFailed, recorder.status("job-1"))
+	assert.Equal(t, models.JobStatusFailed, recorder.status("job-2"))
+}
+
+func TestWorkerPool_ShutdownDrainsQueue(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	processor := newBlockingProcessor()
+
+	pool := NewWorkerPool(processor, newStatusRecorder(), log, 1, 2, time.Minute)
+	pool.Start()
+
+	require.NoError(t, pool.Enqueue(newTestJob("job-1")))
+	require.NoError(t, pool.Enqueue(newTestJob("job-2")))
+	close(processor.release)
+
+	require.NoError(t, pool.Shutdown(context.Background()))
+	assert.Len(t, processor.started, 2)
+	assert.ErrorIs(t, pool.Enqueue(newTestJob("job-3")), ErrShuttingDown)
+}
+
+func TestWorkerPool_ShutdownFailsLeftoverJobs(t *testing.T) {
+	log := logger.NewLogger("error", "console")
+	recorder := newStatusRecorder()
+	processor := newBlockingProcessor()
+	defer close(processor.release)
+
+	pool := NewWorkerPool(processor, recorder, log, 1, 1, time.Minute)
+	pool.Start()
+
+	require.NoError(t, pool.Enqueue(newTestJob("running-job")))
+	<-processor.started
+	require.NoError(t, pool.Enqueue(newTestJob("queued-job")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, pool.Shutdown(ctx), context.DeadlineExceeded)
+	assert.Equal(t, models.JobStatusFailed, recorder.status("running-job"))
+	assert.Equal(t, models.JobStatusFailed, recorder.status("queued-job"))
+}
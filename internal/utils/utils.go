@@ -15,6 +15,37 @@ func ParseDate(dateStr string) (time.Time, error) {
 	return time.Parse("2006-01-02", dateStr)
 }
 
+// ParseDueDate returns when a todo is due. An RFC 3339 due time is used
+// as is; a bare YYYY-MM-DD date means the start of that day in loc. It
+// returns nil when neither value is usable.
+func ParseDueDate(dueDate, dueTime *string, loc *time.Location) *time.Time {
+	if dueTime != nil && *dueTime != "" {
+		if at, err := time.Parse(time.RFC3339, *dueTime); err == nil {
+			return &at
+		}
+	}
+
+	if dueDate != nil && *dueDate != "" {
+		if day, err := time.ParseInLocation("2006-01-02", *dueDate, loc); err == nil {
+			return &day
+		}
+	}
+
+	return nil
+}
+
+// LoadLocation returns the named IANA timezone, or UTC when name is empty or unknown
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
 // CreateDirIfNotExists creates directory if it doesn't exist
 func CreateDirIfNotExists(dir string) error {
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
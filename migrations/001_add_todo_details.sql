@@ -0,0 +1,11 @@
+-- Adds the details extracted from meeting notes: priority, labels,
+-- estimated duration and the person responsible.
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS priority text
+        CHECK (priority IN ('low', 'medium', 'high')),
+    ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS estimated_minutes integer
+        CHECK (estimated_minutes > 0),
+    ADD COLUMN IF NOT EXISTS assignee text;
+
+CREATE INDEX IF NOT EXISTS todos_tags_idx ON todos USING gin (tags);
@@ -0,0 +1,7 @@
+-- Subtasks point to the todo they belong to. Deleting a todo removes its
+-- checklist with it.
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS parent_id uuid
+        REFERENCES todos (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS todos_parent_id_idx ON todos (parent_id);
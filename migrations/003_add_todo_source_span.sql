@@ -0,0 +1,4 @@
+-- Records which part of the input each todo came from: the excerpt, its
+-- character offsets in the extracted text and the PDF page.
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS source_span jsonb;
@@ -0,0 +1,8 @@
+-- Lets extraction compare new todos with the user's open ones. Todos that
+-- likely repeat an existing todo point to it when they are flagged.
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS completed boolean NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS duplicate_of uuid
+        REFERENCES todos (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS todos_open_idx ON todos (user_id) WHERE NOT completed;
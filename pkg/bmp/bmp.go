@@ -0,0 +1,252 @@
+// Package bmp decodes Windows bitmap images so they can be re-encoded in
+// a format the vision models accept. Uncompressed 1, 4, 8, 16, 24 and
+// 32 bit images are supported, with or without bit field masks. RLE and
+// embedded JPEG or PNG bitmaps are rejected.
+package bmp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math/bits"
+)
+
+var (
+	ErrInvalid     = errors.New("not a valid BMP file")
+	ErrUnsupported = errors.New("unsupported BMP format")
+)
+
+// maxPixels bounds the memory a decoded image may take
+const maxPixels = 1 << 25
+
+const (
+	fileHeaderSize = 14
+	coreHeaderSize = 12
+	infoHeaderSize = 40
+
+	compressionRGB       = 0
+	compressionBitfields = 3
+)
+
+// header holds the fields of the file and DIB headers that decoding needs
+type header struct {
+	offset      int
+	size        int
+	width       int
+	height      int
+	topDown     bool
+	bitCount    int
+	compression uint32
+	colorsUsed  int
+	masks       [3]uint32
+}
+
+// Decode decodes a BMP image. Paletted images decode to *image.Paletted
+// and all others to *image.RGBA.
+func Decode(data []byte) (image.Image, error) {
+	h, err := readHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rowSize := (h.width*h.bitCount + 31) / 32 * 4
+	if h.offset > len(data) || rowSize*h.height > len(data)-h.offset {
+		return nil, fmt.Errorf("%w: pixel data is truncated", ErrInvalid)
+	}
+
+	if h.bitCount <= 8 {
+		palette, err := readPalette(data, h)
+		if err != nil {
+			return nil, err
+		}
+		return decodePaletted(data, h, rowSize, palette)
+	}
+	return decodeRGBA(data, h, rowSize), nil
+}
+
+// readHeader parses and validates the file and DIB headers
+func readHeader(data []byte) (header, error) {
+	var h header
+
+	if len(data) < fileHeaderSize+4 || string(data[:2]) != "BM" {
+		return h, ErrInvalid
+	}
+	h.offset = int(binary.LittleEndian.Uint32(data[10:]))
+	h.size = int(binary.LittleEndian.Uint32(data[fileHeaderSize:]))
+
+	dib := data[fileHeaderSize:]
+	if h.size < coreHeaderSize || len(dib) < h.size {
+		return h, fmt.Errorf("%w: truncated header", ErrInvalid)
+	}
+
+	if h.size == coreHeaderSize {
+		h.width = int(binary.LittleEndian.Uint16(dib[4:]))
+		h.height = int(binary.LittleEndian.Uint16(dib[6:]))
+		h.bitCount = int(binary.LittleEndian.Uint16(dib[10:]))
+	} else {
+		if h.size < infoHeaderSize {
+			return h, fmt.Errorf("%w: header size %d", ErrUnsupported, h.size)
+		}
+		h.width = int(int32(binary.LittleEndian.Uint32(dib[4:])))
+		height := int(int32(binary.LittleEndian.Uint32(dib[8:])))
+		if height < 0 {
+			height, h.topDown = -height, true
+		}
+		h.height = height
+		h.bitCount = int(binary.LittleEndian.Uint16(dib[14:]))
+		h.compression = binary.LittleEndian.Uint32(dib[16:])
+		h.colorsUsed = int(binary.LittleEndian.Uint32(dib[32:]))
+	}
+
+	if h.width <= 0 || h.height <= 0 {
+		return h, fmt.Errorf("%w: image is %dx%d", ErrInvalid, h.width, h.height)
+	}
+	if h.width > maxPixels/h.height {
+		return h, fmt.Errorf("%w: image is %dx%d", ErrUnsupported, h.width, h.height)
+	}
+
+	switch h.bitCount {
+	case 1, 4, 8, 24:
+		if h.compression != compressionRGB {
+			return h, fmt.Errorf("%w: compression %d", ErrUnsupported, h.compression)
+		}
+	case 16, 32:
+		if err := readMasks(data, &h); err != nil {
+			return h, err
+		}
+	default:
+		return h, fmt.Errorf("%w: %d bits per pixel", ErrUnsupported, h.bitCount)
+	}
+
+	return h, nil
+}
+
+// readMasks sets the channel masks of a 16 or 32 bit image. Masks follow
+// a BITMAPINFOHEADER and are part of the later, larger headers.
+func readMasks(data []byte, h *header) error {
+	switch h.compression {
+	case compressionRGB:
+		if h.bitCount == 16 {
+			h.masks = [3]uint32{0x7c00, 0x03e0, 0x001f}
+		} else {
+			h.masks = [3]uint32{0xff0000, 0x00ff00, 0x0000ff}
+		}
+		return nil
+
+	case compressionBitfields:
+		start := fileHeaderSize + infoHeaderSize
+		if len(data) < start+12 {
+			return fmt.Errorf("%w: truncated bit field masks", ErrInvalid)
+		}
+		for i := range h.masks {
+			h.masks[i] = binary.LittleEndian.Uint32(data[start+4*i:])
+			if h.masks[i] == 0 {
+				return fmt.Errorf("%w: empty bit field mask", ErrInvalid)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%w: compression %d", ErrUnsupported, h.compression)
+	}
+}
+
+// readPalette reads the color table that follows the headers
+func readPalette(data []byte, h header) (color.Palette, error) {
+	count := h.colorsUsed
+	if count == 0 || count > 1<<h.bitCount {
+		count = 1 << h.bitCount
+	}
+
+	// Core headers store BGR triples, the others BGR plus a reserved byte
+	entrySize := 4
+	if h.size == coreHeaderSize {
+		entrySize = 3
+	}
+
+	start := fileHeaderSize + h.size
+	if start+count*entrySize > h.offset {
+		return nil, fmt.Errorf("%w: truncated color table", ErrInvalid)
+	}
+
+	palette := make(color.Palette, count)
+	for i := range palette {
+		entry := data[start+i*entrySize:]
+		palette[i] = color.RGBA{R: entry[2], G: entry[1], B: entry[0], A: 0xff}
+	}
+	return palette, nil
+}
+
+func decodePaletted(data []byte, h header, rowSize int, palette color.Palette) (image.Image, error) {
+	img := image.NewPaletted(image.Rect(0, 0, h.width, h.height), palette)
+	perByte := 8 / h.bitCount
+	mask := byte(1<<h.bitCount - 1)
+
+	for y := 0; y < h.height; y++ {
+		row := data[h.offset+rowOffset(h, y, rowSize):]
+		for x := 0; x < h.width; x++ {
+			shift := uint(8 - h.bitCount*(x%perByte+1))
+			index := row[x/perByte] >> shift & mask
+			if int(index) >= len(palette) {
+				return nil, fmt.Errorf("%w: color index %d outside the color table", ErrInvalid, index)
+			}
+			img.Pix[y*img.Stride+x] = index
+		}
+	}
+
+	return img, nil
+}
+
+func decodeRGBA(data []byte, h header, rowSize int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, h.width, h.height))
+	bytesPerPixel := h.bitCount / 8
+
+	for y := 0; y < h.height; y++ {
+		row := data[h.offset+rowOffset(h, y, rowSize):]
+		for x := 0; x < h.width; x++ {
+			pixel := row[x*bytesPerPixel:]
+			out := img.Pix[y*img.Stride+x*4:]
+
+			if h.bitCount == 24 {
+				out[0], out[1], out[2] = pixel[2], pixel[1], pixel[0]
+			} else {
+				var value uint32
+				if h.bitCount == 16 {
+					value = uint32(binary.LittleEndian.Uint16(pixel))
+				} else {
+					value = binary.LittleEndian.Uint32(pixel)
+				}
+				for i, mask := range h.masks {
+					out[i] = channel(value, mask)
+				}
+			}
+			out[3] = 0xff
+		}
+	}
+
+	return img
+}
+
+// rowOffset returns where image row y starts in the pixel data. Rows are
+// stored bottom-up unless the height in the header is negative.
+func rowOffset(h header, y, rowSize int) int {
+	if h.topDown {
+		return y * rowSize
+	}
+	return (h.height - 1 - y) * rowSize
+}
+
+// channel extracts the bits of value selected by mask, scaled to 8 bits
+func channel(value, mask uint32) uint8 {
+	shift := bits.TrailingZeros32(mask)
+	width := bits.OnesCount32(mask >> shift)
+	v := (value & mask) >> shift
+
+	if width >= 8 {
+		return uint8(v >> (width - 8))
+	}
+	max := uint32(1)<<width - 1
+	return uint8(v * 255 / max)
+}
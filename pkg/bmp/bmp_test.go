@@ -0,0 +1,94 @@
+package bmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildBMP assembles a BMP file with a BITMAPINFOHEADER. extra holds
+// the bit field masks or color table, rows the padded pixel rows.
+func buildBMP(width, height, bitCount int, compression uint32, extra []byte, rows ...[]byte) []byte {
+	pixels := bytes.Join(rows, nil)
+	offset := fileHeaderSize + infoHeaderSize + len(extra)
+
+	var buf bytes.Buffer
+	buf.WriteString("BM")
+	binary.Write(&buf, binary.LittleEndian, uint32(offset+len(pixels)))
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	binary.Write(&buf, binary.LittleEndian, uint32(offset))
+
+	binary.Write(&buf, binary.LittleEndian, uint32(infoHeaderSize))
+	binary.Write(&buf, binary.LittleEndian, int32(width))
+	binary.Write(&buf, binary.LittleEndian, int32(height))
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, uint16(bitCount))
+	binary.Write(&buf, binary.LittleEndian, compression)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(pixels)))
+	buf.Write(make([]byte, 16))
+
+	buf.Write(extra)
+	buf.Write(pixels)
+	return buf.Bytes()
+}
+
+func TestDecode_24Bit(t *testing.T) {
+	// Bottom-up: the first stored row is the bottom of the image
+	data := buildBMP(2, 2, 24, compressionRGB, nil,
+		[]byte{0, 0, 255, 0, 255, 0, 0, 0},     // red, green, padding
+		[]byte{255, 0, 0, 255, 255, 255, 0, 0}, // blue, white, padding
+	)
+
+	img, err := Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 2, 2), img.Bounds())
+	assert.Equal(t, color.RGBA{B: 255, A: 255}, img.At(0, 0))
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, img.At(1, 0))
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, img.At(0, 1))
+	assert.Equal(t, color.RGBA{G: 255, A: 255}, img.At(1, 1))
+}
+
+func TestDecode_Paletted(t *testing.T) {
+	palette := []byte{0, 0, 0, 0, 255, 255, 255, 0}
+	data := buildBMP(3, 1, 1, compressionRGB, palette, []byte{0b10100000, 0, 0, 0})
+
+	img, err := Decode(data)
+	require.NoError(t, err)
+	require.IsType(t, &image.Paletted{}, img)
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, img.At(0, 0))
+	assert.Equal(t, color.RGBA{A: 255}, img.At(1, 0))
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, img.At(2, 0))
+}
+
+func TestDecode_Bitfields(t *testing.T) {
+	// 16 bit RGB565, stored top-down
+	masks := []byte{0x00, 0xf8, 0, 0, 0xe0, 0x07, 0, 0, 0x1f, 0x00, 0, 0}
+	data := buildBMP(1, -2, 16, compressionBitfields, masks,
+		[]byte{0x00, 0xf8, 0, 0}, // red
+		[]byte{0x1f, 0x00, 0, 0}, // blue
+	)
+
+	img, err := Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, img.At(0, 0))
+	assert.Equal(t, color.RGBA{B: 255, A: 255}, img.At(0, 1))
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	_, err := Decode([]byte("GIF89a"))
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	data := buildBMP(2, 2, 24, compressionRGB, nil, []byte{0, 0, 255, 0, 255, 0, 0, 0})
+	_, err = Decode(data)
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	// RLE8
+	data = buildBMP(1, 1, 8, 1, make([]byte, 1024), []byte{0, 0, 0, 0})
+	_, err = Decode(data)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
@@ -0,0 +1,77 @@
+// Package cache keeps extraction results so repeated inputs do not have to
+// be sent to the model again
+package cache
+
+import (
+	"errors"
+	"regexp"
+	"time"
+)
+
+// ErrInvalidKey is returned for keys that are empty or not safe as file names
+var ErrInvalidKey = errors.New("invalid cache key")
+
+var validKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Cache stores values by key. Entries expire after a time to live and the
+// least valuable entries are evicted when the cache is full.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte) error
+}
+
+// expiringGetter is a Cache that reports when the value it returns
+// expires. The zero time means it does not expire.
+type expiringGetter interface {
+	GetWithExpiry(key string) ([]byte, time.Time, bool)
+}
+
+// expiringSetter is a Cache that can store a value until a given time
+type expiringSetter interface {
+	SetWithExpiry(key string, value []byte, expires time.Time) error
+}
+
+// Tiered looks up keys in each cache in turn, typically a fast memory
+// cache in front of a persistent one. Values found in a later tier are
+// copied into the earlier ones with the expiry they have in the later
+// tier, and values are stored in every tier.
+type Tiered []Cache
+
+// Get returns the value from the first tier that has key
+func (t Tiered) Get(key string) ([]byte, bool) {
+	for i, tier := range t {
+		getter, ok := tier.(expiringGetter)
+		if !ok {
+			// Without the expiry a copy could outlive the original
+			if value, ok := tier.Get(key); ok {
+				return value, true
+			}
+			continue
+		}
+
+		value, expires, ok := getter.GetWithExpiry(key)
+		if !ok {
+			continue
+		}
+
+		// Best effort: a failed copy only costs a later lookup
+		for _, earlier := range t[:i] {
+			if setter, ok := earlier.(expiringSetter); ok {
+				_ = setter.SetWithExpiry(key, value, expires)
+			}
+		}
+		return value, true
+	}
+	return nil, false
+}
+
+// Set stores the value in every tier and returns the first error
+func (t Tiered) Set(key string, value []byte) error {
+	var firstErr error
+	for _, tier := range t {
+		if err := tier.Set(key, value); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
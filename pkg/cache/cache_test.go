@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewMemory(2, 0)
+
+	require.NoError(t, cache.Set("a", []byte("1")))
+	require.NoError(t, cache.Set("b", []byte("2")))
+
+	// Reading a makes b the least recently used entry
+	_, ok := cache.Get("a")
+	require.True(t, ok)
+	require.NoError(t, cache.Set("c", []byte("3")))
+
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", string(value))
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestMemory_Expires(t *testing.T) {
+	now := time.Date(2025, 7, 15, 9, 0, 0, 0, time.UTC)
+	cache := NewMemory(10, time.Hour)
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.Set("a", []byte("1")))
+
+	now = now.Add(59 * time.Minute)
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestDisk_StoresAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDisk(dir, 2, 0)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, cache.Set(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("value%d", i))))
+
+		// Distinct write times so the oldest entry is well defined
+		at := time.Now().Add(time.Duration(i-3) * time.Minute)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, fmt.Sprintf("key%d.cache", i)), at, at))
+	}
+	require.NoError(t, cache.prune())
+
+	_, ok := cache.Get("key0")
+	assert.False(t, ok)
+	value, ok := cache.Get("key2")
+	assert.True(t, ok)
+	assert.Equal(t, "value2", string(value))
+
+	// A new instance reads what an earlier one wrote
+	reopened, err := NewDisk(dir, 2, 0)
+	require.NoError(t, err)
+	_, ok = reopened.Get("key1")
+	assert.True(t, ok)
+}
+
+func TestDisk_ExpiresAndRejectsUnsafeKeys(t *testing.T) {
+	cache, err := NewDisk(t.TempDir(), 10, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, cache.Set("key", []byte("value")))
+	cache.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, ok := cache.Get("key")
+	assert.False(t, ok)
+
+	assert.ErrorIs(t, cache.Set("../key", []byte("value")), ErrInvalidKey)
+	assert.ErrorIs(t, cache.Set("", []byte("value")), ErrInvalidKey)
+}
+
+func TestTiered_FillsEarlierTiers(t *testing.T) {
+	front := NewMemory(10, 0)
+	back, err := NewDisk(t.TempDir(), 10, 0)
+	require.NoError(t, err)
+	require.NoError(t, back.Set("key", []byte("value")))
+
+	tiered := Tiered{front, back}
+	value, ok := tiered.Get("key")
+	require.True(t, ok)
+	assert.Equal(t, "value", string(value))
+
+	value, ok = front.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", string(value))
+
+	require.NoError(t, tiered.Set("other", []byte("x")))
+	_, ok = back.Get("other")
+	assert.True(t, ok)
+}
+
+func TestTiered_CopiesKeepExpiry(t *testing.T) {
+	now := time.Now()
+	front := NewMemory(10, time.Hour)
+	front.now = func() time.Time { return now }
+	back, err := NewDisk(t.TempDir(), 10, time.Hour)
+	require.NoError(t, err)
+	back.now = func() time.Time { return now }
+	require.NoError(t, back.Set("key", []byte("value")))
+
+	// Found on disk shortly before it expires
+	now = now.Add(59 * time.Minute)
+	tiered := Tiered{front, back}
+	_, ok := tiered.Get("key")
+	require.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = front.Get("key")
+	assert.False(t, ok)
+	_, ok = tiered.Get("key")
+	assert.False(t, ok)
+}
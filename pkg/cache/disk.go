@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Disk keeps values in files so they survive restarts. Entries expire
+// ttl after they were written, and the oldest entries are removed when
+// there are more than maxEntries.
+type Disk struct {
+	dir        string
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mutex sync.Mutex // serializes writes and pruning
+}
+
+// NewDisk creates a cache that stores values below dir
+func NewDisk(dir string, maxEntries int, ttl time.Duration) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	return &Disk{
+		dir:        dir,
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+	}, nil
+}
+
+// Get returns the value stored for key
+func (d *Disk) Get(key string) ([]byte, bool) {
+	value, _, ok := d.GetWithExpiry(key)
+	return value, ok
+}
+
+// GetWithExpiry is Get that also returns when the value expires, or the
+// zero time when it does not
+func (d *Disk) GetWithExpiry(key string) ([]byte, time.Time, bool) {
+	if !validKey.MatchString(key) {
+		return nil, time.Time{}, false
+	}
+
+	path := d.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+
+	if d.expired(info) {
+		os.Remove(path)
+		return nil, time.Time{}, false
+	}
+
+	value, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+
+	var expires time.Time
+	if d.ttl > 0 {
+		expires = info.ModTime().Add(d.ttl)
+	}
+	return value, expires, true
+}
+
+// Set writes value for key, replacing the file atomically, and removes
+// the oldest entries when the cache is full
+func (d *Disk) Set(key string, value []byte) error {
+	if !validKey.MatchString(key) {
+		return ErrInvalidKey
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	tmp, err := os.CreateTemp(d.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), d.path(key)); err != nil {
+		return fmt.Errorf("failed to store cache file: %w", err)
+	}
+
+	return d.prune()
+}
+
+// prune removes expired entries and then the oldest ones above maxEntries
+func (d *Disk) prune() error {
+	dirEntries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list cache directory: %w", err)
+	}
+
+	var live []os.FileInfo
+	for _, dirEntry := range dirEntries {
+		if !strings.HasSuffix(dirEntry.Name(), ".cache") {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		if d.expired(info) {
+			os.Remove(filepath.Join(d.dir, info.Name()))
+			continue
+		}
+		live = append(live, info)
+	}
+
+	if d.maxEntries <= 0 || len(live) <= d.maxEntries {
+		return nil
+	}
+
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].ModTime().Before(live[j].ModTime())
+	})
+	for _, info := range live[:len(live)-d.maxEntries] {
+		os.Remove(filepath.Join(d.dir, info.Name()))
+	}
+
+	return nil
+}
+
+func (d *Disk) expired(info os.FileInfo) bool {
+	return d.ttl > 0 && d.now().After(info.ModTime().Add(d.ttl))
+}
+
+func (d *Disk) path(key string) string {
+	return filepath.Join(d.dir, key+".cache")
+}
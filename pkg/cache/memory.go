@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Memory is a least recently used cache held in memory
+type Memory struct {
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mutex   sync.Mutex
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemory creates a cache of at most maxEntries values that expire ttl
+// after they are stored. A ttl of zero keeps values until they are evicted.
+func NewMemory(maxEntries int, ttl time.Duration) *Memory {
+	return &Memory{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value stored for key and marks it as recently used
+func (m *Memory) Get(key string) ([]byte, bool) {
+	value, _, ok := m.GetWithExpiry(key)
+	return value, ok
+}
+
+// GetWithExpiry is Get that also returns when the value expires, or the
+// zero time when it does not
+func (m *Memory) GetWithExpiry(key string) ([]byte, time.Time, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+
+	entry := element.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && m.now().After(entry.expires) {
+		m.remove(element)
+		return nil, time.Time{}, false
+	}
+
+	m.order.MoveToFront(element)
+	return entry.value, entry.expires, true
+}
+
+// Set stores value for key, evicting the least recently used entries when
+// the cache is full
+func (m *Memory) Set(key string, value []byte) error {
+	return m.SetWithExpiry(key, value, time.Time{})
+}
+
+// SetWithExpiry is Set for a value that expires at expires, or the zero
+// time for none. The value never outlives the cache's own time to live.
+func (m *Memory) SetWithExpiry(key string, value []byte, expires time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.ttl > 0 {
+		if limit := m.now().Add(m.ttl); expires.IsZero() || expires.After(limit) {
+			expires = limit
+		}
+	}
+
+	if element, ok := m.entries[key]; ok {
+		entry := element.Value.(*memoryEntry)
+		entry.value = value
+		entry.expires = expires
+		m.order.MoveToFront(element)
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryEntry{key: key, value: value, expires: expires})
+
+	for m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		m.remove(m.order.Back())
+	}
+
+	return nil
+}
+
+// Len returns the number of stored entries, including expired ones not yet removed
+func (m *Memory) Len() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.order.Len()
+}
+
+func (m *Memory) remove(element *list.Element) {
+	m.order.Remove(element)
+	delete(m.entries, element.Value.(*memoryEntry).key)
+}
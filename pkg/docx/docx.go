@@ -0,0 +1,289 @@
+// Package docx extracts plain text from Word (.docx) documents.
+// Paragraphs become lines, list items keep their bullet or number and
+// table rows are written with their cells separated by " | ".
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalid = errors.New("not a valid DOCX file")
+	ErrNoText  = errors.New("DOCX contains no text")
+)
+
+// maxPartSize bounds how much XML is read from a single archive part
+const maxPartSize = 50 << 20
+
+// ExtractText returns the text of the main document part
+func ExtractText(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", ErrInvalid
+	}
+
+	document, err := readPart(zr, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	if document == nil {
+		return "", fmt.Errorf("%w: word/document.xml is missing", ErrInvalid)
+	}
+
+	var lists *numbering
+	if part, err := readPart(zr, "word/numbering.xml"); err == nil && part != nil {
+		lists = parseNumbering(part)
+	}
+
+	text, err := render(document, lists)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", ErrNoText
+	}
+
+	return text, nil
+}
+
+// readPart returns the contents of a part, or nil if the archive does not have it
+func readPart(zr *zip.Reader, partName string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != partName {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", partName, err)
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(io.LimitReader(rc, maxPartSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", partName, err)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// numbering holds the list formats declared in word/numbering.xml
+type numbering struct {
+	// formats maps numId and level to a number format such as "decimal" or "bullet"
+	formats map[string]map[int]string
+}
+
+func (n *numbering) format(numID string, level int) string {
+	if n == nil {
+		return "bullet"
+	}
+	if f, ok := n.formats[numID][level]; ok {
+		return f
+	}
+	return "bullet"
+}
+
+func parseNumbering(data []byte) *numbering {
+	abstract := make(map[string]map[int]string)
+	numToAbstract := make(map[string]string)
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var abstractID, numID string
+	level := -1
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "abstractNum":
+			abstractID = attr(start, "abstractNumId")
+			abstract[abstractID] = make(map[int]string)
+			numID = ""
+		case "lvl":
+			level, _ = strconv.Atoi(attr(start, "ilvl"))
+		case "numFmt":
+			if levels, ok := abstract[abstractID]; ok && numID == "" && level >= 0 {
+				levels[level] = attr(start, "val")
+			}
+		case "num":
+			numID = attr(start, "numId")
+		case "abstractNumId":
+			if numID != "" {
+				numToAbstract[numID] = attr(start, "val")
+			}
+		}
+	}
+
+	n := &numbering{formats: make(map[string]map[int]string)}
+	for num, abs := range numToAbstract {
+		n.formats[num] = abstract[abs]
+	}
+	return n
+}
+
+func attr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// renderer walks word/document.xml and writes plain text
+type renderer struct {
+	lists *numbering
+	out   strings.Builder
+
+	para   strings.Builder
+	inText bool
+	numID  string
+	level  int
+
+	tableDepth int
+	cell       strings.Builder
+	cells      []string
+
+	// counters tracks the next number per list and level
+	counters map[string][]int
+}
+
+func render(data []byte, lists *numbering) (string, error) {
+	r := &renderer{
+		lists:    lists,
+		counters: make(map[string][]int),
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			r.start(t)
+		case xml.EndElement:
+			r.end(t)
+		case xml.CharData:
+			if r.inText {
+				r.para.Write(t)
+			}
+		}
+	}
+
+	return strings.TrimSpace(r.out.String()), nil
+}
+
+func (r *renderer) start(el xml.StartElement) {
+	switch el.Name.Local {
+	case "p":
+		r.para.Reset()
+		r.numID, r.level = "", 0
+	case "t":
+		r.inText = true
+	case "tab":
+		r.para.WriteByte('\t')
+	case "br", "cr":
+		r.para.WriteByte('\n')
+	case "numId":
+		r.numID = attr(el, "val")
+	case "ilvl":
+		r.level, _ = strconv.Atoi(attr(el, "val"))
+	case "tbl":
+		r.tableDepth++
+	case "tc":
+		if r.tableDepth == 1 {
+			r.cell.Reset()
+		}
+	}
+}
+
+func (r *renderer) end(el xml.EndElement) {
+	switch el.Name.Local {
+	case "t":
+		r.inText = false
+	case "p":
+		r.endParagraph()
+	case "tc":
+		if r.tableDepth == 1 {
+			r.cells = append(r.cells, strings.TrimSpace(r.cell.String()))
+		}
+	case "tr":
+		if r.tableDepth == 1 {
+			r.out.WriteString(strings.Join(r.cells, " | "))
+			r.out.WriteByte('\n')
+			r.cells = nil
+		}
+	case "tbl":
+		r.tableDepth--
+		if r.tableDepth == 0 {
+			r.out.WriteByte('\n')
+		}
+	}
+}
+
+func (r *renderer) endParagraph() {
+	text := r.para.String()
+	if r.numID != "" && r.numID != "0" && strings.TrimSpace(text) != "" {
+		text = r.listPrefix() + text
+	}
+
+	if r.tableDepth > 0 {
+		if r.cell.Len() > 0 {
+			r.cell.WriteByte(' ')
+		}
+		r.cell.WriteString(text)
+		return
+	}
+
+	r.out.WriteString(text)
+	r.out.WriteByte('\n')
+}
+
+// listPrefix returns the indentation and marker of the current list item
+func (r *renderer) listPrefix() string {
+	level := r.level
+	if level < 0 || level > 8 {
+		level = 0
+	}
+	indent := strings.Repeat("  ", level)
+
+	format := r.lists.format(r.numID, level)
+	if format == "bullet" || format == "none" || format == "" {
+		return indent + "- "
+	}
+
+	// Numbering restarts below the current level
+	counts := r.counters[r.numID]
+	if len(counts) < 9 {
+		counts = make([]int, 9)
+	}
+	counts[level]++
+	for i := level + 1; i < len(counts); i++ {
+		counts[i] = 0
+	}
+	r.counters[r.numID] = counts
+
+	return fmt.Sprintf("%s%d. ", indent, counts[level])
+}
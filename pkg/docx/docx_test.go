@@ -0,0 +1,95 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const wordNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+
+func buildDOCX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for partName, content := range parts {
+		w, err := zw.Create(partName)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func paragraph(text string) string {
+	return `<w:p><w:r><w:t xml:space="preserve">` + text + `</w:t></w:r></w:p>`
+}
+
+func listItem(numID, level, text string) string {
+	return `<w:p><w:pPr><w:numPr><w:ilvl w:val="` + level + `"/><w:numId w:val="` + numID + `"/></w:numPr></w:pPr>` +
+		`<w:r><w:t>` + text + `</w:t></w:r></w:p>`
+}
+
+func TestExtractText(t *testing.T) {
+	document := `<?xml version="1.0" encoding="UTF-8"?><w:document ` + wordNS + `><w:body>` +
+		paragraph("Notulen rapat") +
+		`<w:p><w:r><w:t>Hadir:</w:t></w:r><w:r><w:tab/><w:t>Budi</w:t></w:r></w:p>` +
+		listItem("1", "0", "Kirim laporan") +
+		listItem("1", "1", "Cek angka") +
+		listItem("2", "0", "Siapkan demo") +
+		listItem("2", "0", "Booking ruangan") +
+		`<w:tbl>` +
+		`<w:tr><w:tc>` + paragraph("Tugas") + `</w:tc><w:tc>` + paragraph("PIC") + `</w:tc></w:tr>` +
+		`<w:tr><w:tc>` + paragraph("Update API") + `</w:tc><w:tc>` + paragraph("Sari") + `</w:tc></w:tr>` +
+		`</w:tbl>` +
+		`<w:p><w:r><w:delText>removed</w:delText></w:r></w:p>` +
+		`</w:body></w:document>`
+
+	numbering := `<?xml version="1.0" encoding="UTF-8"?><w:numbering ` + wordNS + `>` +
+		`<w:abstractNum w:abstractNumId="10"><w:lvl w:ilvl="0"><w:numFmt w:val="bullet"/></w:lvl><w:lvl w:ilvl="1"><w:numFmt w:val="bullet"/></w:lvl></w:abstractNum>` +
+		`<w:abstractNum w:abstractNumId="20"><w:lvl w:ilvl="0"><w:numFmt w:val="decimal"/></w:lvl></w:abstractNum>` +
+		`<w:num w:numId="1"><w:abstractNumId w:val="10"/></w:num>` +
+		`<w:num w:numId="2"><w:abstractNumId w:val="20"/></w:num>` +
+		`</w:numbering>`
+
+	data := buildDOCX(t, map[string]string{
+		"word/document.xml":  document,
+		"word/numbering.xml": numbering,
+	})
+
+	text, err := ExtractText(data)
+	require.NoError(t, err)
+
+	expected := "Notulen rapat\n" +
+		"Hadir:\tBudi\n" +
+		"- Kirim laporan\n" +
+		"  - Cek angka\n" +
+		"1. Siapkan demo\n" +
+		"2. Booking ruangan\n" +
+		"Tugas | PIC\n" +
+		"Update API | Sari"
+	assert.Equal(t, expected, text)
+}
+
+func TestExtractText_Invalid(t *testing.T) {
+	_, err := ExtractText([]byte("not a zip"))
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	_, err = ExtractText(buildDOCX(t, map[string]string{"content.xml": "<x/>"}))
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestExtractText_Empty(t *testing.T) {
+	data := buildDOCX(t, map[string]string{
+		"word/document.xml": `<w:document ` + wordNS + `><w:body><w:p/></w:body></w:document>`,
+	})
+
+	_, err := ExtractText(data)
+	assert.ErrorIs(t, err, ErrNoText)
+}
@@ -2,34 +2,108 @@ package gemini
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
+
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/pkg/prompt"
+	"todo-agent-backend/pkg/retry"
 )
 
 const (
 	DefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
 	DefaultModel   = "gemini-1.5-flash"
+	DefaultTimeout = 30 * time.Second
 )
 
 type Client struct {
-	apiKey     string
-	model      string
-	baseURL    string
-	httpClient *http.Client
+	apiKey      string
+	model       string
+	baseURL     string
+	httpClient  *http.Client
+	retryPolicy retry.Policy
+	counter     *retry.Counter
+	extraction  prompt.Extraction
 }
 
 type GenerateRequest struct {
-	Contents []Content `json:"contents"`
+	Contents         []Content         `json:"contents"`
+	GenerationConfig *GenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// GenerationConfig constrains the model output
+type GenerationConfig struct {
+	ResponseMimeType string  `json:"responseMimeType,omitempty"`
+	ResponseSchema   *Schema `json:"responseSchema,omitempty"`
+}
+
+// Schema is the OpenAPI subset Gemini accepts for structured output
+type Schema struct {
+	Type       string             `json:"type"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Enum       []string           `json:"enum,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+}
+
+// todoListSchema describes the todo array the model must reply with
+var todoListSchema = &Schema{
+	Type:  "ARRAY",
+	Items: todoSchema(prompt.MaxSubtaskDepth),
+}
+
+// todoSchema describes a single todo. The schema cannot refer to itself,
+// so subtasks are spelled out down to the allowed depth.
+func todoSchema(depth int) *Schema {
+	schema := &Schema{
+		Type: "OBJECT",
+		Properties: map[string]*Schema{
+			"title":       {Type: "STRING"},
+			"description": {Type: "STRING"},
+			"due_date":    {Type: "STRING", Nullable: true},
+			"due_time":    {Type: "STRING", Nullable: true},
+			"priority": {
+				Type:     "STRING",
+				Format:   "enum",
+				Enum:     []string{models.PriorityLow, models.PriorityMedium, models.PriorityHigh},
+				Nullable: true,
+			},
+			"tags":              {Type: "ARRAY", Items: &Schema{Type: "STRING"}},
+			"estimated_minutes": {Type: "INTEGER", Nullable: true},
+			"assignee":          {Type: "STRING", Nullable: true},
+			"source_quote":      {Type: "STRING", Nullable: true},
+		},
+		Required: []string{"title"},
+	}
+
+	if depth > 0 {
+		schema.Properties["subtasks"] = &Schema{Type: "ARRAY", Items: todoSchema(depth - 1)}
+	}
+
+	return schema
 }
 
 type Content struct {
+	Role  string `json:"role,omitempty"`
 	Parts []Part `json:"parts"`
 }
 
 type Part struct {
-	Text string `json:"text"`
+	Text       string      `json:"text,omitempty"`
+	InlineData *InlineData `json:"inline_data,omitempty"`
+}
+
+// InlineData carries binary content such as an image inside a request
+type InlineData struct {
+	MimeType string `json:"mime_type"`
+	Data     string `json:"data"` // base64 encoded
 }
 
 type GenerateResponse struct {
@@ -40,89 +114,176 @@ type Candidate struct {
 	Content Content `json:"content"`
 }
 
-type TodoItem struct {
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	DueDate     *string `json:"due_date"`
-}
-
-func NewClient(apiKey, model string) *Client {
+func NewClient(apiKey, model string, timeout time.Duration, retryPolicy retry.Policy) *Client {
 	if model == "" {
 		model = DefaultModel
 	}
 
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
 	return &Client{
 		apiKey:  apiKey,
 		model:   model,
 		baseURL: DefaultBaseURL,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: timeout,
 		},
+		retryPolicy: retryPolicy,
 	}
 }
 
-func (c *Client) ExtractTodos(text string) ([]TodoItem, error) {
-	prompt := c.buildPrompt(text)
+// Model returns the name of the model the client sends requests to
+func (c *Client) Model() string {
+	return c.model
+}
+
+// WithBaseURL returns a copy of the client that sends requests to baseURL
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	clone := *c
+	clone.baseURL = strings.TrimSuffix(baseURL, "/")
+	return &clone
+}
+
+// WithRetryCounter returns a copy of the client that records every request attempt in counter
+func (c *Client) WithRetryCounter(counter *retry.Counter) *Client {
+	clone := *c
+	clone.counter = counter
+	return &clone
+}
+
+// WithMaxRepairRounds returns a copy of the client that asks the model to
+// correct an invalid reply up to rounds times before failing
+func (c *Client) WithMaxRepairRounds(rounds int) *Client {
+	clone := *c
+	clone.extraction.MaxRepairRounds = rounds
+	return &clone
+}
+
+// WithRepairCounter returns a copy of the client that records every repair round in counter
+func (c *Client) WithRepairCounter(counter *retry.Counter) *Client {
+	clone := *c
+	clone.extraction.Repairs = counter
+	return &clone
+}
+
+// WithPrompt returns a copy of the client that renders its instructions from p
+func (c *Client) WithPrompt(p prompt.Prompt) *Client {
+	clone := *c
+	clone.extraction.Prompt = p
+	return &clone
+}
 
+// ExtractTodos extracts todos from plain text
+func (c *Client) ExtractTodos(ctx context.Context, text string) ([]models.TodoItem, error) {
+	instructions, err := c.extraction.Prompt.ForText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.extract(ctx, []Part{
+		{Text: instructions},
+	})
+}
+
+// ExtractTodosFromImage extracts todos from an image, such as a photo of
+// handwritten notes, by sending it inline together with the prompt
+func (c *Client) ExtractTodosFromImage(ctx context.Context, image []byte, mimeType string) ([]models.TodoItem, error) {
+	instructions, err := c.extraction.Prompt.ForImage()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.extract(ctx, []Part{
+		{Text: instructions},
+		{InlineData: &InlineData{
+			MimeType: mimeType,
+			Data:     base64.StdEncoding.EncodeToString(image),
+		}},
+	})
+}
+
+// extract sends the given parts and parses the todo list from the reply,
+// continuing the conversation for repair rounds
+func (c *Client) extract(ctx context.Context, parts []Part) ([]models.TodoItem, error) {
+	contents := []Content{
+		{Role: "user", Parts: parts},
+	}
+
+	return c.extraction.Run(ctx, func(ctx context.Context, followUp *prompt.FollowUp) (string, error) {
+		if followUp != nil {
+			contents = append(contents,
+				Content{Role: "model", Parts: []Part{{Text: followUp.Reply}}},
+				Content{Role: "user", Parts: []Part{{Text: followUp.Repair}}},
+			)
+		}
+		return c.send(ctx, contents)
+	})
+}
+
+// send makes a generateContent request, retrying transient failures, and returns the reply text
+func (c *Client) send(ctx context.Context, contents []Content) (string, error) {
 	request := GenerateRequest{
-		Contents: []Content{
-			{
-				Parts: []Part{
-					{Text: prompt},
-				},
-			},
+		Contents: contents,
+		GenerationConfig: &GenerationConfig{
+			ResponseMimeType: "application/json",
+			ResponseSchema:   todoListSchema,
 		},
 	}
 
-	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", c.baseURL, c.model, c.apiKey)
+	url := fmt.Sprintf("%s/models/%s:generateContent", c.baseURL, c.model)
 
 	jsonData, err := json.Marshal(request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	var response GenerateResponse
+	err = c.retryPolicy.Do(ctx, func(attempt int) error {
+		c.counter.Add(1)
+		return c.generate(ctx, url, jsonData, &response)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request to Gemini API: %w", err)
+		return "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Gemini API returned status %d", resp.StatusCode)
+	if len(response.Candidates) == 0 || len(response.Candidates[0].Content.Parts) == 0 {
+		return "", fmt.Errorf("empty response from Gemini API")
 	}
 
-	var response GenerateResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	var responseText strings.Builder
+	for _, part := range response.Candidates[0].Content.Parts {
+		responseText.WriteString(part.Text)
 	}
 
-	if len(response.Candidates) == 0 || len(response.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("empty response from Gemini API")
+	return responseText.String(), nil
+}
+
+// generate makes a single generateContent request.
+// The API key is sent as a header so it never shows up in error messages.
+func (c *Client) generate(ctx context.Context, url string, body []byte, response *GenerateResponse) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	responseText := response.Candidates[0].Content.Parts[0].Text
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", c.apiKey)
 
-	// Parse JSON response
-	var todos []TodoItem
-	if err := json.Unmarshal([]byte(responseText), &todos); err != nil {
-		return nil, fmt.Errorf("failed to parse todos from response: %w", err)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request to Gemini API: %w", err)
 	}
+	defer resp.Body.Close()
 
-	return todos, nil
-}
-
-func (c *Client) buildPrompt(text string) string {
-	return fmt.Sprintf(`Anda adalah asisten produktivitas. Dari teks berikut, ekstrak daftar todo dalam format JSON:
-[{"title":"...","description":"...","due_date":"YYYY-MM-DD|null"}]
+	if resp.StatusCode != http.StatusOK {
+		return retry.NewHTTPError("Gemini API", resp)
+	}
 
-ATURAN:
-1. Ekstrak hanya tugas/aktivitas yang perlu dilakukan
-2. Jangan termasuk hal yang sudah selesai
-3. due_date harus format YYYY-MM-DD atau null jika tidak ada tanggal
-4. description boleh kosong jika tidak ada detail
-5. Response harus valid JSON array
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
 
-Teks:
----
-%s`, text)
+	return nil
 }
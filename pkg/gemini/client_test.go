@@ -0,0 +1,190 @@
+package gemini
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo-agent-backend/pkg/prompt"
+	"todo-agent-backend/pkg/retry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGemini serves generateContent and records the last request
+type fakeGemini struct {
+	server   *httptest.Server
+	requests []GenerateRequest
+	replies  []string
+}
+
+func newFakeGemini(t *testing.T, replies ...string) *fakeGemini {
+	fake := &fakeGemini{replies: replies}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models/test-model:generateContent", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-goog-api-key"))
+
+		var request GenerateRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		fake.requests = append(fake.requests, request)
+
+		reply := fake.replies[0]
+		fake.replies = fake.replies[1:]
+
+		json.NewEncoder(w).Encode(GenerateResponse{
+			Candidates: []Candidate{{Content: Content{Parts: []Part{{Text: reply}}}}},
+		})
+	}))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func newTestClient(baseURL string) *Client {
+	return NewClient("test-key", "test-model", time.Second, retry.NewPolicy(0)).WithBaseURL(baseURL)
+}
+
+func TestExtractTodos(t *testing.T) {
+	fake := newFakeGemini(t, `[{"title":"Send report","description":"","due_date":"2025-07-18"}]`)
+
+	todos, err := newTestClient(fake.server.URL).ExtractTodos(context.Background(), "Send report by Friday")
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "Send report", todos[0].Title)
+	require.NotNil(t, todos[0].DueDate)
+	assert.Equal(t, "2025-07-18", *todos[0].DueDate)
+
+	parts := fake.requests[0].Contents[0].Parts
+	require.Len(t, parts, 1)
+	assert.Contains(t, parts[0].Text, "Send report by Friday")
+
+	config := fake.requests[0].GenerationConfig
+	require.NotNil(t, config)
+	assert.Equal(t, "application/json", config.ResponseMimeType)
+	require.NotNil(t, config.ResponseSchema)
+	assert.Equal(t, "ARRAY", config.ResponseSchema.Type)
+	assert.Equal(t, []string{"title"}, config.ResponseSchema.Items.Required)
+	assert.True(t, config.ResponseSchema.Items.Properties["due_date"].Nullable)
+
+	// Subtasks are nested down to the allowed depth and no further
+	subtasks := config.ResponseSchema.Items.Properties["subtasks"]
+	require.NotNil(t, subtasks)
+	require.NotNil(t, subtasks.Items.Properties["subtasks"])
+	assert.Nil(t, subtasks.Items.Properties["subtasks"].Items.Properties["subtasks"])
+}
+
+func TestExtractTodos_FencedReply(t *testing.T) {
+	fake := newFakeGemini(t, "```json\n[{\"title\":\"Send report\",\"due_date\":null}]\n```")
+
+	todos, err := newTestClient(fake.server.URL).ExtractTodos(context.Background(), "Send report")
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "Send report", todos[0].Title)
+}
+
+func TestExtractTodos_InvalidItem(t *testing.T) {
+	fake := newFakeGemini(t, `[{"title":"","due_date":null}]`)
+
+	_, err := newTestClient(fake.server.URL).ExtractTodos(context.Background(), "Send report")
+	assert.ErrorIs(t, err, prompt.ErrInvalidResponse)
+}
+
+func TestExtractTodos_RepairsInvalidReply(t *testing.T) {
+	fake := newFakeGemini(t, `[{"title":""}]`, `[{"title":"Send report"}]`)
+	repairs := &retry.Counter{}
+
+	todos, err := newTestClient(fake.server.URL).
+		WithMaxRepairRounds(2).
+		WithRepairCounter(repairs).
+		ExtractTodos(context.Background(), "Send report")
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "Send report", todos[0].Title)
+	assert.Equal(t, 1, repairs.Attempts())
+
+	require.Len(t, fake.requests, 2)
+	contents := fake.requests[1].Contents
+	require.Len(t, contents, 3)
+	assert.Equal(t, "model", contents[1].Role)
+	assert.Equal(t, `[{"title":""}]`, contents[1].Parts[0].Text)
+	assert.Equal(t, "user", contents[2].Role)
+	assert.Contains(t, contents[2].Parts[0].Text, "title is required")
+}
+
+func TestExtractTodos_RepairBudgetExhausted(t *testing.T) {
+	fake := newFakeGemini(t, "not json", "still not json")
+	repairs := &retry.Counter{}
+
+	_, err := newTestClient(fake.server.URL).
+		WithMaxRepairRounds(1).
+		WithRepairCounter(repairs).
+		ExtractTodos(context.Background(), "Send report")
+	assert.ErrorIs(t, err, prompt.ErrInvalidResponse)
+	assert.Len(t, fake.requests, 2)
+	assert.Equal(t, 1, repairs.Attempts())
+}
+
+func TestExtractTodosFromImage(t *testing.T) {
+	fake := newFakeGemini(t, `[{"title":"Call Budi","description":"","due_date":null}]`)
+	image := []byte{0xff, 0xd8, 0xff, 0xe0}
+
+	todos, err := newTestClient(fake.server.URL).ExtractTodosFromImage(context.Background(), image, "image/jpeg")
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "Call Budi", todos[0].Title)
+	assert.Nil(t, todos[0].DueDate)
+
+	parts := fake.requests[0].Contents[0].Parts
+	require.Len(t, parts, 2)
+	assert.NotEmpty(t, parts[0].Text)
+	require.NotNil(t, parts[1].InlineData)
+	assert.Equal(t, "image/jpeg", parts[1].InlineData.MimeType)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(image), parts[1].InlineData.Data)
+}
+
+func TestExtractTodos_RetriesServerErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(GenerateResponse{
+			Candidates: []Candidate{{Content: Content{Parts: []Part{{Text: `[]`}}}}},
+		})
+	}))
+	defer server.Close()
+
+	policy := retry.Policy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	counter := &retry.Counter{}
+	client := NewClient("test-key", "test-model", time.Second, policy).WithBaseURL(server.URL).WithRetryCounter(counter)
+
+	todos, err := client.ExtractTodos(context.Background(), "nothing to do")
+	require.NoError(t, err)
+	assert.Empty(t, todos)
+	assert.Equal(t, 2, counter.Attempts())
+}
+
+func TestExtractTodos_ContextCancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := newTestClient(server.URL).ExtractTodos(ctx, "Send report")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
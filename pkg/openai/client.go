@@ -0,0 +1,223 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/pkg/prompt"
+	"todo-agent-backend/pkg/retry"
+)
+
+const (
+	DefaultBaseURL = "https://api.openai.com/v1"
+	DefaultTimeout = 60 * time.Second
+)
+
+// Client talks to any server implementing the OpenAI chat completions API,
+// such as OpenAI itself, llama.cpp server or Ollama
+type Client struct {
+	apiKey      string
+	model       string
+	baseURL     string
+	httpClient  *http.Client
+	retryPolicy retry.Policy
+	counter     *retry.Counter
+	extraction  prompt.Extraction
+}
+
+type ChatRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature float64   `json:"temperature"`
+}
+
+// Message content is either a string or a list of ContentParts
+type Message struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+type ChatResponse struct {
+	Choices []Choice `json:"choices"`
+}
+
+type Choice struct {
+	Message ResponseMessage `json:"message"`
+}
+
+type ResponseMessage struct {
+	Content string `json:"content"`
+}
+
+func NewClient(baseURL, apiKey, model string, timeout time.Duration, retryPolicy retry.Policy) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Client{
+		apiKey:  apiKey,
+		model:   model,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		retryPolicy: retryPolicy,
+	}
+}
+
+// WithRetryCounter returns a copy of the client that records every request attempt in counter
+func (c *Client) WithRetryCounter(counter *retry.Counter) *Client {
+	clone := *c
+	clone.counter = counter
+	return &clone
+}
+
+// Model returns the name of the model the client sends requests to
+func (c *Client) Model() string {
+	return c.model
+}
+
+// WithMaxRepairRounds returns a copy of the client that asks the model to
+// correct an invalid reply up to rounds times before failing
+func (c *Client) WithMaxRepairRounds(rounds int) *Client {
+	clone := *c
+	clone.extraction.MaxRepairRounds = rounds
+	return &clone
+}
+
+// WithRepairCounter returns a copy of the client that records every repair round in counter
+func (c *Client) WithRepairCounter(counter *retry.Counter) *Client {
+	clone := *c
+	clone.extraction.Repairs = counter
+	return &clone
+}
+
+// WithPrompt returns a copy of the client that renders its instructions from p
+func (c *Client) WithPrompt(p prompt.Prompt) *Client {
+	clone := *c
+	clone.extraction.Prompt = p
+	return &clone
+}
+
+// ExtractTodos extracts todos from plain text
+func (c *Client) ExtractTodos(ctx context.Context, text string) ([]models.TodoItem, error) {
+	instructions, err := c.extraction.Prompt.ForText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.extract(ctx, instructions)
+}
+
+// ExtractTodosFromImage extracts todos from an image sent as a data URL.
+// The configured model must accept image input.
+func (c *Client) ExtractTodosFromImage(ctx context.Context, image []byte, mimeType string) ([]models.TodoItem, error) {
+	instructions, err := c.extraction.Prompt.ForImage()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.extract(ctx, []ContentPart{
+		{Type: "text", Text: instructions},
+		{Type: "image_url", ImageURL: &ImageURL{
+			URL: fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(image)),
+		}},
+	})
+}
+
+// extract sends a single user message and parses the todo list from the
+// reply, continuing the conversation for repair rounds
+func (c *Client) extract(ctx context.Context, content interface{}) ([]models.TodoItem, error) {
+	messages := []Message{
+		{Role: "user", Content: content},
+	}
+
+	return c.extraction.Run(ctx, func(ctx context.Context, followUp *prompt.FollowUp) (string, error) {
+		if followUp != nil {
+			messages = append(messages,
+				Message{Role: "assistant", Content: followUp.Reply},
+				Message{Role: "user", Content: followUp.Repair},
+			)
+		}
+		return c.send(ctx, messages)
+	})
+}
+
+// send makes a chat completions request, retrying transient failures, and returns the reply text
+func (c *Client) send(ctx context.Context, messages []Message) (string, error) {
+	request := ChatRequest{
+		Model:       c.model,
+		Messages:    messages,
+		Temperature: 0,
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var response ChatResponse
+	err = c.retryPolicy.Do(ctx, func(attempt int) error {
+		c.counter.Add(1)
+		return c.complete(ctx, jsonData, &response)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("empty response from chat completions API")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
+
+// complete makes a single chat completions request
+func (c *Client) complete(ctx context.Context, body []byte, response *ChatResponse) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make request to chat completions API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return retry.NewHTTPError("chat completions API", resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
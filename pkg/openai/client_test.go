@@ -0,0 +1,139 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo-agent-backend/pkg/prompt"
+	"todo-agent-backend/pkg/retry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer serves chat completions and records the raw requests
+type fakeServer struct {
+	server   *httptest.Server
+	requests []map[string]interface{}
+	replies  []string
+}
+
+func newFakeServer(t *testing.T, replies ...string) *fakeServer {
+	fake := &fakeServer{replies: replies}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		fake.requests = append(fake.requests, request)
+
+		reply := fake.replies[0]
+		fake.replies = fake.replies[1:]
+
+		json.NewEncoder(w).Encode(ChatResponse{
+			Choices: []Choice{{Message: ResponseMessage{Content: reply}}},
+		})
+	}))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func newTestClient(baseURL string) *Client {
+	return NewClient(baseURL+"/v1/", "test-key", "llama3", time.Second, retry.NewPolicy(0))
+}
+
+func TestExtractTodos(t *testing.T) {
+	fake := newFakeServer(t, `[{"title":"Send report","description":"","due_date":"2025-07-18"}]`)
+
+	todos, err := newTestClient(fake.server.URL).ExtractTodos(context.Background(), "Send report by Friday")
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "Send report", todos[0].Title)
+	require.NotNil(t, todos[0].DueDate)
+	assert.Equal(t, "2025-07-18", *todos[0].DueDate)
+
+	request := fake.requests[0]
+	assert.Equal(t, "llama3", request["model"])
+	messages := request["messages"].([]interface{})
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0].(map[string]interface{})["content"], "Send report by Friday")
+}
+
+func TestExtractTodosFromImage(t *testing.T) {
+	fake := newFakeServer(t, `[{"title":"Call Budi","description":"","due_date":null}]`)
+
+	todos, err := newTestClient(fake.server.URL).ExtractTodosFromImage(context.Background(), []byte{0xff, 0xd8}, "image/jpeg")
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Nil(t, todos[0].DueDate)
+
+	message := fake.requests[0]["messages"].([]interface{})[0].(map[string]interface{})
+	parts := message["content"].([]interface{})
+	require.Len(t, parts, 2)
+	image := parts[1].(map[string]interface{})
+	assert.Equal(t, "image_url", image["type"])
+	assert.Equal(t, "data:image/jpeg;base64,/9g=", image["image_url"].(map[string]interface{})["url"])
+}
+
+func TestExtractTodos_RetriesServerErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(ChatResponse{
+			Choices: []Choice{{Message: ResponseMessage{Content: `[]`}}},
+		})
+	}))
+	defer server.Close()
+
+	policy := retry.Policy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	counter := &retry.Counter{}
+	client := NewClient(server.URL, "", "llama3", time.Second, policy).WithRetryCounter(counter)
+
+	todos, err := client.ExtractTodos(context.Background(), "nothing to do")
+	require.NoError(t, err)
+	assert.Empty(t, todos)
+	assert.Equal(t, 2, counter.Attempts())
+}
+
+func TestExtractTodos_RepairsInvalidReply(t *testing.T) {
+	fake := newFakeServer(t, `[{"title":""}]`, `[{"title":"Send report"}]`)
+	repairs := &retry.Counter{}
+
+	todos, err := newTestClient(fake.server.URL).
+		WithMaxRepairRounds(2).
+		WithRepairCounter(repairs).
+		ExtractTodos(context.Background(), "Send report")
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "Send report", todos[0].Title)
+	assert.Equal(t, 1, repairs.Attempts())
+
+	require.Len(t, fake.requests, 2)
+	messages := fake.requests[1]["messages"].([]interface{})
+	require.Len(t, messages, 3)
+	assert.Equal(t, "assistant", messages[1].(map[string]interface{})["role"])
+	assert.Equal(t, `[{"title":""}]`, messages[1].(map[string]interface{})["content"])
+	assert.Contains(t, messages[2].(map[string]interface{})["content"], "title is required")
+}
+
+func TestExtractTodos_RepairBudgetExhausted(t *testing.T) {
+	fake := newFakeServer(t, "not json", "still not json")
+	repairs := &retry.Counter{}
+
+	_, err := newTestClient(fake.server.URL).
+		WithMaxRepairRounds(1).
+		WithRepairCounter(repairs).
+		ExtractTodos(context.Background(), "Send report")
+	assert.ErrorIs(t, err, prompt.ErrInvalidResponse)
+	assert.Len(t, fake.requests, 2)
+	assert.Equal(t, 1, repairs.Attempts())
+}
@@ -0,0 +1,258 @@
+package pdf
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// font maps character codes in shown strings to Unicode text
+type font struct {
+	// toUnicode holds mappings from a ToUnicode CMap, keyed by code
+	toUnicode map[uint32]string
+	codeLen   int
+	// encoding maps single-byte codes for simple fonts
+	encoding *[256]rune
+}
+
+// defaultFont decodes strings when no font is selected or it cannot be loaded
+var defaultFont = &font{codeLen: 1, encoding: &winAnsiEncoding}
+
+func (r *reader) loadFont(d dict) *font {
+	if d == nil {
+		return defaultFont
+	}
+
+	f := &font{codeLen: 1}
+	composite := d["Subtype"] == name("Type0")
+	if composite {
+		f.codeLen = 2
+	}
+
+	if s, ok := r.resolve(d["ToUnicode"]).(stream); ok {
+		if data, err := r.decodeStream(s); err == nil {
+			f.toUnicode, f.codeLen = parseCMap(data, f.codeLen)
+		}
+	}
+
+	if !composite {
+		f.encoding = r.simpleEncoding(d["Encoding"])
+	}
+
+	return f
+}
+
+// simpleEncoding builds the code table of a simple font from its /Encoding entry
+func (r *reader) simpleEncoding(obj object) *[256]rune {
+	enc := winAnsiEncoding
+
+	switch e := r.resolve(obj).(type) {
+	case dict:
+		if diffs, ok := r.resolve(e["Differences"]).(array); ok {
+			code := 0
+			for _, item := range diffs {
+				switch v := r.resolve(item).(type) {
+				case int:
+					code = v
+				case name:
+					if code >= 0 && code < len(enc) {
+						if ch, ok := glyphRune(string(v)); ok {
+							enc[code] = ch
+						}
+					}
+					code++
+				}
+			}
+		}
+	}
+
+	return &enc
+}
+
+func (f *font) decode(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		n := f.codeLen
+		if i+n > len(s) {
+			n = len(s) - i
+		}
+
+		var code uint32
+		for j := 0; j < n; j++ {
+			code = code<<8 | uint32(s[i+j])
+		}
+		i += n
+
+		if text, ok := f.toUnicode[code]; ok {
+			sb.WriteString(text)
+			continue
+		}
+		if f.encoding != nil && n == 1 {
+			if ch := f.encoding[code]; ch != 0 {
+				sb.WriteRune(ch)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// parseCMap reads bfchar and bfrange mappings from a ToUnicode CMap.
+// It also returns the code length declared by the codespace ranges.
+func parseCMap(data []byte, codeLen int) (map[uint32]string, int) {
+	mapping := make(map[uint32]string)
+	l := newLexer(data)
+
+	var operands []object
+	for {
+		l.skipSpace()
+		if l.eof() {
+			break
+		}
+		obj, err := l.readObject()
+		if err != nil {
+			break
+		}
+
+		op, isOp := obj.(keyword)
+		if !isOp {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "endcodespacerange":
+			if len(operands) >= 1 {
+				if lo, ok := operands[0].(string); ok && len(lo) > 0 {
+					codeLen = len(lo)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(string)
+				dst, ok2 := operands[i+1].(string)
+				if ok1 && ok2 {
+					mapping[codeValue(src)] = utf16String(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(string)
+				hi, ok2 := operands[i+1].(string)
+				if !ok1 || !ok2 {
+					continue
+				}
+				addRange(mapping, codeValue(lo), codeValue(hi), operands[i+2])
+			}
+		}
+		operands = operands[:0]
+	}
+
+	return mapping, codeLen
+}
+
+// maxRangeSize guards against huge bfrange entries in malformed CMaps
+const maxRangeSize = 1 << 16
+
+func addRange(mapping map[uint32]string, lo, hi uint32, dst object) {
+	if hi < lo || hi-lo > maxRangeSize {
+		return
+	}
+
+	switch d := dst.(type) {
+	case string:
+		base := []rune(utf16String(d))
+		if len(base) == 0 {
+			return
+		}
+		for code := lo; code <= hi; code++ {
+			out := append([]rune(nil), base...)
+			out[len(out)-1] += rune(code - lo)
+			mapping[code] = string(out)
+		}
+	case array:
+		for i, item := range d {
+			code := lo + uint32(i)
+			if code > hi {
+				break
+			}
+			if s, ok := item.(string); ok {
+				mapping[code] = utf16String(s)
+			}
+		}
+	}
+}
+
+func codeValue(s string) uint32 {
+	var v uint32
+	for i := 0; i < len(s); i++ {
+		v = v<<8 | uint32(s[i])
+	}
+	return v
+}
+
+func utf16String(s string) string {
+	if len(s)%2 == 1 {
+		return s
+	}
+	units := make([]uint16, len(s)/2)
+	for i := range units {
+		units[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// glyphRune maps a glyph name from a Differences array to a rune
+func glyphRune(glyph string) (rune, bool) {
+	if ch, ok := glyphNames[glyph]; ok {
+		return ch, true
+	}
+	if len(glyph) == 1 {
+		return rune(glyph[0]), true
+	}
+	if strings.HasPrefix(glyph, "uni") && len(glyph) == 7 {
+		if v, err := strconv.ParseUint(glyph[3:], 16, 16); err == nil {
+			return rune(v), true
+		}
+	}
+	return 0, false
+}
+
+// glyphNames covers the glyph names commonly found in Differences arrays
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#',
+	"dollar": '$', "percent": '%', "ampersand": '&', "quotesingle": '\'',
+	"parenleft": '(', "parenright": ')', "asterisk": '*', "plus": '+',
+	"comma": ',', "hyphen": '-', "period": '.', "slash": '/',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"colon": ':', "semicolon": ';', "less": '<', "equal": '=',
+	"greater": '>', "question": '?', "at": '@', "bracketleft": '[',
+	"backslash": '\\', "bracketright": ']', "underscore": '_',
+	"braceleft": '{', "bar": '|', "braceright": '}', "asciitilde": '~',
+	"quoteleft": '‘', "quoteright": '’', "quotedblleft": '“',
+	"quotedblright": '”', "bullet": '•', "endash": '–',
+	"emdash": '—', "ellipsis": '…', "fi": 'ﬁ', "fl": 'ﬂ',
+	"minus": '−', "degree": '°', "copyright": '©',
+	"registered": '®', "trademark": '™', "Euro": '€',
+}
+
+// winAnsiEncoding is Windows-1252, used for simple fonts without a ToUnicode map
+var winAnsiEncoding = func() [256]rune {
+	var enc [256]rune
+	for i := 0x20; i < 0x7f; i++ {
+		enc[i] = rune(i)
+	}
+	for i := 0xa0; i < 0x100; i++ {
+		enc[i] = rune(i)
+	}
+	enc['\t'], enc['\n'], enc['\r'] = '\t', '\n', '\r'
+
+	high := []rune{
+		0x20ac, 0, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+		0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017d, 0,
+		0, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+		0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0, 0x017e, 0x0178,
+	}
+	copy(enc[0x80:0xa0], high)
+	return enc
+}()
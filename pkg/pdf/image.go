@@ -0,0 +1,152 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// ErrUnsupportedImage is returned for images that cannot be extracted,
+// such as inline images or CCITT and JBIG2 scans
+var ErrUnsupportedImage = errors.New("unsupported PDF image encoding")
+
+// maxImagePixels bounds the size of raw images converted to PNG
+const maxImagePixels = 50 << 20
+
+// Image is a picture drawn on a page. Its data is decoded on demand.
+type Image struct {
+	r *reader
+	s *stream
+}
+
+// Extract returns the image as a file in the named format: "jpeg" and
+// "jp2" images are passed through, raw samples are encoded as "png".
+func (img Image) Extract() ([]byte, string, error) {
+	if img.s == nil {
+		return nil, "", ErrUnsupportedImage
+	}
+
+	filters, params := img.r.filters(img.s.hdr)
+	if n := len(filters); n > 0 {
+		var format string
+		switch filters[n-1] {
+		case "DCTDecode", "DCT":
+			format = "jpeg"
+		case "JPXDecode":
+			format = "jp2"
+		}
+		if format != "" {
+			data, err := decodeFilters(img.s.data, filters[:n-1], params[:n-1])
+			if err != nil {
+				return nil, "", err
+			}
+			return data, format, nil
+		}
+	}
+
+	data, err := decodeFilters(img.s.data, filters, params)
+	if err != nil {
+		return nil, "", ErrUnsupportedImage
+	}
+
+	pic, err := img.r.rawImage(img.s.hdr, data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, pic); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "png", nil
+}
+
+// rawImage builds an image from uncompressed samples in gray, RGB or CMYK
+func (r *reader) rawImage(hdr dict, data []byte) (image.Image, error) {
+	width := intOr(r.resolve(hdr["Width"]), 0)
+	height := intOr(r.resolve(hdr["Height"]), 0)
+	bpc := intOr(r.resolve(hdr["BitsPerComponent"]), 8)
+	if width <= 0 || height <= 0 || width*height > maxImagePixels {
+		return nil, ErrUnsupportedImage
+	}
+
+	components := r.colorComponents(hdr["ColorSpace"])
+	if mask, _ := r.resolve(hdr["ImageMask"]).(bool); mask {
+		components, bpc = 1, 1
+	}
+
+	rect := image.Rect(0, 0, width, height)
+	switch {
+	case bpc == 8 && components == 1 && len(data) >= width*height:
+		pic := image.NewGray(rect)
+		copy(pic.Pix, data)
+		return pic, nil
+
+	case bpc == 8 && components == 3 && len(data) >= width*height*3:
+		pic := image.NewRGBA(rect)
+		for i := 0; i < width*height; i++ {
+			pic.Pix[4*i] = data[3*i]
+			pic.Pix[4*i+1] = data[3*i+1]
+			pic.Pix[4*i+2] = data[3*i+2]
+			pic.Pix[4*i+3] = 0xff
+		}
+		return pic, nil
+
+	case bpc == 8 && components == 4 && len(data) >= width*height*4:
+		pic := image.NewCMYK(rect)
+		copy(pic.Pix, data)
+		return pic, nil
+
+	case bpc == 1 && components == 1:
+		rowLen := (width + 7) / 8
+		if len(data) < rowLen*height {
+			return nil, ErrUnsupportedImage
+		}
+		invert := false
+		if decode, ok := r.resolve(hdr["Decode"]).(array); ok && len(decode) > 0 {
+			invert = toFloat(decode[0]) == 1
+		}
+
+		pic := image.NewGray(rect)
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				bit := data[y*rowLen+x/8]>>(7-uint(x%8))&1 == 1
+				if bit != invert {
+					pic.SetGray(x, y, color.Gray{Y: 0xff})
+				}
+			}
+		}
+		return pic, nil
+	}
+
+	return nil, ErrUnsupportedImage
+}
+
+// colorComponents returns the number of color components of a color space,
+// or 0 for color spaces that are not supported
+func (r *reader) colorComponents(obj object) int {
+	switch cs := r.resolve(obj).(type) {
+	case name:
+		switch cs {
+		case "DeviceGray", "CalGray", "G":
+			return 1
+		case "DeviceRGB", "CalRGB", "RGB":
+			return 3
+		case "DeviceCMYK", "CMYK":
+			return 4
+		}
+	case array:
+		if len(cs) == 2 && r.resolve(cs[0]) == name("ICCBased") {
+			return intOr(r.resolveDict(cs[1])["N"], 0)
+		}
+		if len(cs) > 1 && r.resolve(cs[0]) == name("CalRGB") {
+			return 3
+		}
+		if len(cs) > 1 && r.resolve(cs[0]) == name("CalGray") {
+			return 1
+		}
+	}
+	return 0
+}
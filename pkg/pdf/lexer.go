@@ -0,0 +1,342 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// PDF object types produced by the lexer
+type (
+	object  interface{}
+	name    string
+	keyword string
+	array   []object
+	dict    map[name]object
+	ref     struct{ num, gen int }
+	stream  struct {
+		hdr  dict
+		data []byte
+	}
+)
+
+// lexer reads PDF objects from a byte slice. pos never moves past the
+// end of data, so data[pos:] is always valid.
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+func newLexer(data []byte) *lexer {
+	return &lexer{data: data}
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isDelim(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (l *lexer) eof() bool {
+	return l.pos >= len(l.data)
+}
+
+// skipSpace skips whitespace and comments
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isSpace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// readObject reads the next object. Operators in content streams and
+// structural words such as obj or stream come back as keywords.
+func (l *lexer) readObject() (object, error) {
+	l.skipSpace()
+	if l.eof() {
+		return nil, fmt.Errorf("unexpected end of data")
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		return l.readName(), nil
+	case c == '(':
+		return l.readLiteralString()
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			return l.readDict()
+		}
+		return l.readHexString()
+	case c == '[':
+		return l.readArray()
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		l.pos++
+		return keyword(string(c)), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.readNumberOrRef()
+	default:
+		kw := l.readKeyword()
+		switch kw {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return kw, nil
+	}
+}
+
+func (l *lexer) readName() name {
+	l.pos++ // skip '/'
+	var buf bytes.Buffer
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isSpace(c) || isDelim(c) {
+			break
+		}
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				buf.WriteByte(byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		buf.WriteByte(c)
+		l.pos++
+	}
+	return name(buf.String())
+}
+
+func (l *lexer) readKeyword() keyword {
+	start := l.pos
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isSpace(c) || isDelim(c) {
+			break
+		}
+		l.pos++
+	}
+	if l.pos == start {
+		// Stray delimiter, consume it so parsing always advances
+		l.pos++
+	}
+	return keyword(l.data[start:l.pos])
+}
+
+func (l *lexer) readToken() []byte {
+	start := l.pos
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isSpace(c) || isDelim(c) {
+			break
+		}
+		l.pos++
+	}
+	return l.data[start:l.pos]
+}
+
+// readNumberOrRef reads a number, or an indirect reference of the form "num gen R"
+func (l *lexer) readNumberOrRef() (object, error) {
+	tok := l.readToken()
+	num, err := parseNumber(tok)
+	if err != nil {
+		return keyword(tok), nil
+	}
+
+	n, isInt := num.(int)
+	if !isInt || n < 0 {
+		return num, nil
+	}
+
+	// Look ahead for "gen R"
+	save := l.pos
+	l.skipSpace()
+	genTok := l.readToken()
+	if gen, err := strconv.Atoi(string(genTok)); err == nil && len(genTok) > 0 {
+		l.skipSpace()
+		if l.pos < len(l.data) && l.data[l.pos] == 'R' && (l.pos+1 == len(l.data) || isSpace(l.data[l.pos+1]) || isDelim(l.data[l.pos+1])) {
+			l.pos++
+			return ref{num: n, gen: gen}, nil
+		}
+	}
+	l.pos = save
+
+	return n, nil
+}
+
+func parseNumber(tok []byte) (object, error) {
+	if i, err := strconv.Atoi(string(tok)); err == nil {
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(string(tok), 64)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (l *lexer) readLiteralString() (object, error) {
+	l.pos++ // skip '('
+	var buf bytes.Buffer
+	depth := 1
+
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+
+		switch c {
+		case '(':
+			depth++
+			buf.WriteByte(c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return string(buf.Bytes()), nil
+			}
+			buf.WriteByte(c)
+		case '\\':
+			if l.pos >= len(l.data) {
+				return string(buf.Bytes()), nil
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			case 'b':
+				buf.WriteByte('\b')
+			case 'f':
+				buf.WriteByte('\f')
+			case '\r':
+				// Line continuation
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					buf.WriteByte(byte(v))
+				} else {
+					buf.WriteByte(e)
+				}
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	return string(buf.Bytes()), nil
+}
+
+func (l *lexer) readHexString() (object, error) {
+	l.pos++ // skip '<'
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		c := l.data[l.pos]
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	if !l.eof() {
+		l.pos++ // skip '>'
+	}
+
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		v, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex string")
+		}
+		out = append(out, byte(v))
+	}
+
+	return string(out), nil
+}
+
+func (l *lexer) readArray() (object, error) {
+	l.pos++ // skip '['
+	var arr array
+	for {
+		l.skipSpace()
+		if l.eof() {
+			return arr, nil
+		}
+		if l.data[l.pos] == ']' {
+			l.pos++
+			return arr, nil
+		}
+		obj, err := l.readObject()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, obj)
+	}
+}
+
+func (l *lexer) readDict() (object, error) {
+	l.pos += 2 // skip '<<'
+	d := make(dict)
+	for {
+		l.skipSpace()
+		if l.eof() {
+			return d, nil
+		}
+		if l.data[l.pos] == '>' {
+			l.pos = min(l.pos+2, len(l.data)) // skip '>>'
+			return d, nil
+		}
+
+		key, err := l.readObject()
+		if err != nil {
+			return nil, err
+		}
+		k, ok := key.(name)
+		if !ok {
+			continue
+		}
+
+		value, err := l.readObject()
+		if err != nil {
+			return nil, err
+		}
+		d[k] = value
+	}
+}
@@ -0,0 +1,184 @@
+// Package pdf extracts the text layer of PDF documents page by page.
+// It supports the common subset of the format needed for text: classic
+// and compressed object storage, Flate/ASCII filters, the page tree,
+// form XObjects and ToUnicode or single-byte font encodings.
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrImageOnly = errors.New("PDF has no text layer, pages contain only images")
+	ErrNoText    = errors.New("PDF contains no text")
+)
+
+// maxPages bounds the page tree walk for malformed files
+const maxPages = 10000
+
+// Page is the text of a single page and the images drawn on it
+type Page struct {
+	Number int
+	Text   string
+	Images []Image
+}
+
+// Document is the extracted content of a PDF file
+type Document struct {
+	Pages []Page
+}
+
+// PageCount returns the number of pages in the document
+func (d *Document) PageCount() int {
+	return len(d.Pages)
+}
+
+// HasText reports whether any page contains text
+func (d *Document) HasText() bool {
+	for _, page := range d.Pages {
+		if strings.TrimSpace(page.Text) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ImageCount returns the number of images drawn across all pages
+func (d *Document) ImageCount() int {
+	total := 0
+	for _, page := range d.Pages {
+		total += len(page.Images)
+	}
+	return total
+}
+
+// Text joins the pages into one string, marking where each page starts
+func (d *Document) Text() string {
+	var sb strings.Builder
+	for i, page := range d.Pages {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "--- Page %d ---\n", page.Number)
+		sb.WriteString(strings.TrimSpace(page.Text))
+	}
+	return sb.String()
+}
+
+// Parse reads a PDF file and extracts the text of every page
+func Parse(data []byte) (*Document, error) {
+	r, err := load(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.encrypted() {
+		return nil, ErrEncrypted
+	}
+
+	pages := r.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: no pages found", ErrInvalid)
+	}
+
+	doc := &Document{Pages: make([]Page, len(pages))}
+	for i, page := range pages {
+		text, images := r.pageText(page)
+		doc.Pages[i] = Page{
+			Number: i + 1,
+			Text:   text,
+			Images: images,
+		}
+	}
+
+	return doc, nil
+}
+
+// ExtractText parses a PDF file and fails when it has no usable text.
+// For ErrImageOnly the parsed document is returned along with the error.
+func ExtractText(data []byte) (*Document, error) {
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !doc.HasText() {
+		if doc.ImageCount() > 0 {
+			return doc, ErrImageOnly
+		}
+		return nil, ErrNoText
+	}
+
+	return doc, nil
+}
+
+// pages returns the page dictionaries in document order, with inherited
+// resources copied onto each page
+func (r *reader) pages() []dict {
+	var pages []dict
+
+	root := r.resolveDict(r.trailer["Root"])
+	if root == nil {
+		root = r.findCatalog()
+	}
+	if root != nil {
+		visited := make(map[int]bool)
+		r.walkPages(root["Pages"], nil, visited, &pages)
+	}
+
+	if len(pages) > 0 {
+		return pages
+	}
+
+	// No usable page tree, fall back to every page object in number order
+	for _, num := range r.sortedObjectNumbers() {
+		if d, ok := r.objects[num].(dict); ok && d["Type"] == name("Page") {
+			pages = append(pages, d)
+		}
+	}
+	return pages
+}
+
+func (r *reader) findCatalog() dict {
+	for _, num := range r.sortedObjectNumbers() {
+		if d, ok := r.objects[num].(dict); ok && d["Type"] == name("Catalog") {
+			return d
+		}
+	}
+	return nil
+}
+
+func (r *reader) walkPages(node object, resources object, visited map[int]bool, pages *[]dict) {
+	if ref, ok := node.(ref); ok {
+		if visited[ref.num] {
+			return
+		}
+		visited[ref.num] = true
+	}
+
+	d := r.resolveDict(node)
+	if d == nil || len(*pages) >= maxPages {
+		return
+	}
+
+	if res, ok := d["Resources"]; ok {
+		resources = res
+	}
+
+	kids, isTree := r.resolve(d["Kids"]).(array)
+	if !isTree || d["Type"] == name("Page") {
+		page := make(dict, len(d)+1)
+		for k, v := range d {
+			page[k] = v
+		}
+		page["Resources"] = resources
+		*pages = append(*pages, page)
+		return
+	}
+
+	for _, kid := range kids {
+		r.walkPages(kid, resources, visited, pages)
+	}
+}
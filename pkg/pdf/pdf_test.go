@@ -0,0 +1,259 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildPDF assembles a PDF file from object bodies numbered from 1.
+// Object 1 must be the catalog.
+func buildPDF(objects []string, trailerExtra string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+
+	offsets := make([]int, len(objects))
+	for i, body := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R %s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailerExtra, xref)
+
+	return buf.Bytes()
+}
+
+func streamObject(dictExtra string, data []byte) string {
+	return fmt.Sprintf("<< /Length %d %s >>\nstream\n%s\nendstream", len(data), dictExtra, data)
+}
+
+func flateStream(content string) string {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(content))
+	w.Close()
+	return streamObject("/Filter /FlateDecode", buf.Bytes())
+}
+
+func textPagesPDF(contents ...string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // page tree, filled below
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}
+
+	kids := ""
+	for _, content := range contents {
+		pageNum := len(objects) + 1
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Contents %d 0 R >>", pageNum+1),
+			flateStream(content),
+		)
+		kids += fmt.Sprintf("%d 0 R ", pageNum)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /Resources << /Font << /F1 3 0 R >> >> >>", kids, len(contents))
+
+	return buildPDF(objects, "")
+}
+
+func TestExtractText_PreservesPages(t *testing.T) {
+	data := textPagesPDF(
+		"BT /F1 12 Tf 72 720 Td (Rapat tim jam 10) Tj 0 -14 Td (Kirim laporan \\(final\\)) Tj ET",
+		"BT /F1 12 Tf 72 720 Td [(Beli) -300 (kopi)] TJ T* (Bayar listrik) ' ET",
+	)
+
+	doc, err := ExtractText(data)
+	require.NoError(t, err)
+
+	require.Equal(t, 2, doc.PageCount())
+	assert.Equal(t, "Rapat tim jam 10\nKirim laporan (final)", doc.Pages[0].Text)
+	assert.Equal(t, "Beli kopi\nBayar listrik", doc.Pages[1].Text)
+	assert.Equal(t, "--- Page 1 ---\nRapat tim jam 10\nKirim laporan (final)\n\n--- Page 2 ---\nBeli kopi\nBayar listrik", doc.Text())
+}
+
+func TestExtractText_ToUnicode(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+2 beginbfchar <0001> <0054> <0002> <00F6> endbfchar
+1 beginbfrange <0003> <0004> <0064> endbfrange
+endcmap`
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		flateStream("BT /F1 12 Tf 72 720 Td <0001000200030004> Tj ET"),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /ToUnicode 6 0 R >>",
+		streamObject("", []byte(cmap)),
+	}
+
+	doc, err := ExtractText(buildPDF(objects, ""))
+	require.NoError(t, err)
+	assert.Equal(t, "Töde", doc.Pages[0].Text)
+}
+
+func TestExtractText_FormXObject(t *testing.T) {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /XObject << /X1 5 0 R >> /Font << /F1 6 0 R >> >> >>",
+		flateStream("q /X1 Do Q"),
+		streamObject("/Type /XObject /Subtype /Form /BBox [0 0 100 100]", []byte("BT /F1 10 Tf (Inside form) Tj ET")),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	doc, err := ExtractText(buildPDF(objects, ""))
+	require.NoError(t, err)
+	assert.Equal(t, "Inside form", doc.Pages[0].Text)
+}
+
+func TestExtractText_Encrypted(t *testing.T) {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		streamObject("", []byte("garbled")),
+		"<< /Filter /Standard /V 2 /R 3 /Length 128 /P -4 >>",
+	}
+
+	_, err := ExtractText(buildPDF(objects, "/Encrypt 5 0 R"))
+	assert.ErrorIs(t, err, ErrEncrypted)
+}
+
+func TestExtractText_ImageOnly(t *testing.T) {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /XObject << /Im1 5 0 R >> >> >>",
+		flateStream("q 612 0 0 792 0 0 cm /Im1 Do Q"),
+		streamObject("/Type /XObject /Subtype /Image /Width 1 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode", []byte{0xff, 0xd8, 0xff, 0xd9}),
+	}
+
+	doc, err := ExtractText(buildPDF(objects, ""))
+	assert.ErrorIs(t, err, ErrImageOnly)
+	require.NotNil(t, doc)
+	assert.Equal(t, 1, doc.ImageCount())
+
+	data, format, err := doc.Pages[0].Images[0].Extract()
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, []byte{0xff, 0xd8, 0xff, 0xd9}, data)
+}
+
+func TestImage_ExtractRawSamples(t *testing.T) {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /XObject << /Im1 5 0 R /Im2 6 0 R >> >> >>",
+		flateStream("/Im1 Do /Im2 Do BI /W 1 /H 1 /BPC 8 /CS /G ID \x00 EI"),
+		streamObject("/Type /XObject /Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8", []byte{0x00, 0xff}),
+		streamObject("/Type /XObject /Subtype /Image /Width 8 /Height 1 /ImageMask true /Decode [1 0]", []byte{0xf0}),
+	}
+
+	doc, err := Parse(buildPDF(objects, ""))
+	require.NoError(t, err)
+	require.Len(t, doc.Pages[0].Images, 3)
+
+	data, format, err := doc.Pages[0].Images[0].Extract()
+	require.NoError(t, err)
+	assert.Equal(t, "png", format)
+	pic, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, color.Gray{Y: 0xff}, color.GrayModel.Convert(pic.At(1, 0)))
+
+	data, _, err = doc.Pages[0].Images[1].Extract()
+	require.NoError(t, err)
+	pic, err = png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, color.Gray{Y: 0}, color.GrayModel.Convert(pic.At(0, 0)))
+	assert.Equal(t, color.Gray{Y: 0xff}, color.GrayModel.Convert(pic.At(7, 0)))
+
+	// Inline images are counted but not extracted
+	_, _, err = doc.Pages[0].Images[2].Extract()
+	assert.ErrorIs(t, err, ErrUnsupportedImage)
+}
+
+func TestExtractText_NoText(t *testing.T) {
+	_, err := ExtractText(textPagesPDF("0 0 m 100 100 l S"))
+	assert.ErrorIs(t, err, ErrNoText)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]byte("hello world"))
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestParse_ObjectStream(t *testing.T) {
+	// Page tree packed in an object stream, as written by PDF 1.5+ tools
+	pagesObj := "<< /Type /Pages /Kids [3 0 R] /Count 1 >> "
+	pageObj := "<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>"
+	header := fmt.Sprintf("2 0 3 %d ", len(pagesObj))
+	packed := header + pagesObj + pageObj
+	first := len(header)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"null",
+		"null",
+		flateStream("BT (Packed page) Tj ET"),
+		streamObject(fmt.Sprintf("/Type /ObjStm /N 2 /First %d", first), []byte(packed)),
+	}
+	// Drop the placeholders so objects 2 and 3 only exist inside the stream
+	data := buildPDF(objects, "")
+	data = bytes.Replace(data, []byte("2 0 obj\nnull\nendobj\n"), nil, 1)
+	data = bytes.Replace(data, []byte("3 0 obj\nnull\nendobj\n"), nil, 1)
+
+	doc, err := ExtractText(data)
+	require.NoError(t, err)
+	assert.Equal(t, "Packed page", doc.Pages[0].Text)
+}
+
+func TestInflate_Limit(t *testing.T) {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(make([]byte, maxStreamSize+1))
+	w.Close()
+
+	_, err := inflate(buf.Bytes())
+	assert.ErrorIs(t, err, errStreamTooLarge)
+
+	out, err := inflate(buf.Bytes()[:1024])
+	require.NoError(t, err)
+	assert.NotEmpty(t, out)
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add(textPagesPDF("BT /F1 12 Tf 72 720 Td (Rapat tim jam 10) Tj T* [(Beli) -300 (kopi)] TJ ET"))
+	f.Add(buildPDF([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> /XObject << /Im1 7 0 R >> >> >>",
+		streamObject("", []byte("BT /F1 12 Tf <0001000200030004> Tj ET /Im1 Do BI /W 1 /H 1 /BPC 8 /CS /G ID \x00 EI")),
+		"<< /Type /Font /Subtype /Type0 /Encoding /Identity-H /ToUnicode 6 0 R >>",
+		streamObject("", []byte("1 beginbfchar <0001> <0054> endbfchar 1 beginbfrange <0003> <0004> <0064> endbfrange")),
+		streamObject("/Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceGray /BitsPerComponent 8 /DecodeParms << /Predictor 12 /Columns 2 >>", []byte{0x00, 0xff}),
+	}, ""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		doc, err := Parse(data)
+		if err != nil {
+			return
+		}
+		for _, page := range doc.Pages {
+			for _, img := range page.Images {
+				img.Extract()
+			}
+		}
+	})
+}
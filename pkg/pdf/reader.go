@@ -0,0 +1,466 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var (
+	ErrInvalid   = errors.New("not a valid PDF file")
+	ErrEncrypted = errors.New("PDF is encrypted")
+
+	errStreamTooLarge = fmt.Errorf("stream expands to more than %d bytes", maxStreamSize)
+)
+
+// maxResolveDepth guards against reference cycles in malformed files
+const maxResolveDepth = 32
+
+// maxStreamSize bounds how much a single compressed stream may expand to
+const maxStreamSize = 50 << 20
+
+var objHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// reader holds every object of a PDF file keyed by object number
+type reader struct {
+	objects map[int]object
+	trailer dict
+}
+
+// load scans data for indirect objects. Scanning instead of following
+// the cross-reference table copes with broken offsets and incremental
+// updates: later definitions of an object replace earlier ones.
+func load(data []byte) (*reader, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, ErrInvalid
+	}
+
+	r := &reader{
+		objects: make(map[int]object),
+		trailer: make(dict),
+	}
+
+	end := 0
+	for _, m := range objHeader.FindAllSubmatchIndex(data, -1) {
+		if m[0] < end {
+			continue
+		}
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+
+		obj, next, err := readIndirect(data, m[1])
+		if err != nil {
+			continue
+		}
+		r.objects[num] = obj
+		end = next
+	}
+
+	if len(r.objects) == 0 {
+		return nil, ErrInvalid
+	}
+
+	r.readTrailers(data)
+	r.expandObjectStreams()
+
+	return r, nil
+}
+
+// readIndirect reads the body of an indirect object starting right after
+// the "obj" keyword. It returns the object and the offset where it ends.
+func readIndirect(data []byte, pos int) (object, int, error) {
+	if pos < 0 || pos > len(data) {
+		return nil, 0, fmt.Errorf("object offset %d outside the file", pos)
+	}
+
+	l := newLexer(data)
+	l.pos = pos
+
+	obj, err := l.readObject()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hdr, isDict := obj.(dict)
+	if !isDict {
+		return obj, l.pos, nil
+	}
+
+	save := l.pos
+	l.skipSpace()
+	if l.pos > len(data) {
+		return nil, 0, fmt.Errorf("object at offset %d runs past the end of the file", pos)
+	}
+	if !bytes.HasPrefix(data[l.pos:], []byte("stream")) {
+		l.pos = save
+		return hdr, l.pos, nil
+	}
+
+	start := l.pos + len("stream")
+	if start < len(data) && data[start] == '\r' {
+		start++
+	}
+	if start < len(data) && data[start] == '\n' {
+		start++
+	}
+
+	body, next := streamBody(data, start, hdr)
+	return stream{hdr: hdr, data: body}, next, nil
+}
+
+// streamBody returns the raw bytes of a stream. A direct /Length is
+// trusted when endstream follows it; otherwise the data runs up to the
+// next endstream keyword.
+func streamBody(data []byte, start int, hdr dict) ([]byte, int) {
+	if length, ok := hdr["Length"].(int); ok && length >= 0 && start+length <= len(data) {
+		rest := bytes.TrimLeft(data[start+length:], "\r\n \t")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			return data[start : start+length], start + length
+		}
+	}
+
+	idx := bytes.Index(data[start:], []byte("endstream"))
+	if idx < 0 {
+		return data[start:], len(data)
+	}
+	body := data[start : start+idx]
+	body = bytes.TrimSuffix(body, []byte("\n"))
+	body = bytes.TrimSuffix(body, []byte("\r"))
+	return body, start + idx
+}
+
+// readTrailers collects the document root and encryption entries from
+// classic trailers and cross-reference streams
+func (r *reader) readTrailers(data []byte) {
+	pos := 0
+	for {
+		idx := bytes.Index(data[pos:], []byte("trailer"))
+		if idx < 0 {
+			break
+		}
+		l := newLexer(data)
+		l.pos = pos + idx + len("trailer")
+		pos = l.pos
+
+		obj, err := l.readObject()
+		if err != nil {
+			continue
+		}
+		if d, ok := obj.(dict); ok {
+			r.mergeTrailer(d)
+		}
+	}
+
+	for _, num := range r.sortedObjectNumbers() {
+		if s, ok := r.objects[num].(stream); ok && s.hdr["Type"] == name("XRef") {
+			r.mergeTrailer(s.hdr)
+		}
+	}
+}
+
+func (r *reader) mergeTrailer(d dict) {
+	for _, key := range []name{"Root", "Encrypt", "Info"} {
+		if v, ok := d[key]; ok {
+			r.trailer[key] = v
+		}
+	}
+}
+
+// expandObjectStreams loads objects packed inside /Type /ObjStm streams.
+// Objects defined directly in the file take precedence.
+func (r *reader) expandObjectStreams() {
+	for _, num := range r.sortedObjectNumbers() {
+		s, ok := r.objects[num].(stream)
+		if !ok || s.hdr["Type"] != name("ObjStm") {
+			continue
+		}
+
+		data, err := r.decodeStream(s)
+		if err != nil {
+			continue
+		}
+
+		n, _ := r.resolve(s.hdr["N"]).(int)
+		first, _ := r.resolve(s.hdr["First"]).(int)
+		if first <= 0 || first > len(data) {
+			continue
+		}
+
+		l := newLexer(data[:first])
+		for i := 0; i < n; i++ {
+			objNum, err1 := l.readObject()
+			offset, err2 := l.readObject()
+			if err1 != nil || err2 != nil {
+				break
+			}
+			on, ok1 := objNum.(int)
+			off, ok2 := offset.(int)
+			if !ok1 || !ok2 || off < 0 || first+off >= len(data) {
+				continue
+			}
+			if _, exists := r.objects[on]; exists {
+				continue
+			}
+
+			body := newLexer(data)
+			body.pos = first + off
+			obj, err := body.readObject()
+			if err != nil {
+				continue
+			}
+			r.objects[on] = obj
+		}
+	}
+}
+
+func (r *reader) sortedObjectNumbers() []int {
+	nums := make([]int, 0, len(r.objects))
+	for num := range r.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// resolve follows indirect references
+func (r *reader) resolve(obj object) object {
+	for i := 0; i < maxResolveDepth; i++ {
+		ref, ok := obj.(ref)
+		if !ok {
+			return obj
+		}
+		obj = r.objects[ref.num]
+	}
+	return nil
+}
+
+func (r *reader) resolveDict(obj object) dict {
+	switch v := r.resolve(obj).(type) {
+	case dict:
+		return v
+	case stream:
+		return v.hdr
+	}
+	return nil
+}
+
+func (r *reader) encrypted() bool {
+	_, ok := r.trailer["Encrypt"]
+	return ok
+}
+
+// decodeStream applies the stream's filters. Image codecs are reported
+// as unsupported; see Image.Extract.
+func (r *reader) decodeStream(s stream) ([]byte, error) {
+	filters, params := r.filters(s.hdr)
+	return decodeFilters(s.data, filters, params)
+}
+
+func decodeFilters(data []byte, filters []name, params []dict) ([]byte, error) {
+	for i, filter := range filters {
+		var err error
+		switch filter {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data)
+			if err == nil {
+				data, err = unpredict(data, params[i])
+			}
+		case "ASCIIHexDecode", "AHx":
+			data, err = decodeASCIIHex(data)
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		default:
+			return nil, fmt.Errorf("unsupported filter %s", filter)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filter, err)
+		}
+	}
+
+	return data, nil
+}
+
+// filters returns the filter names of a stream and their decode parameters
+func (r *reader) filters(hdr dict) ([]name, []dict) {
+	var filters []name
+	var params []dict
+
+	switch f := r.resolve(hdr["Filter"]).(type) {
+	case name:
+		filters = append(filters, f)
+	case array:
+		for _, item := range f {
+			if n, ok := r.resolve(item).(name); ok {
+				filters = append(filters, n)
+			}
+		}
+	}
+
+	switch p := r.resolve(hdr["DecodeParms"]).(type) {
+	case dict:
+		params = append(params, p)
+	case array:
+		for _, item := range p {
+			params = append(params, r.resolveDict(item))
+		}
+	}
+	for len(params) < len(filters) {
+		params = append(params, nil)
+	}
+
+	return filters, params
+}
+
+// inflate decompresses zlib data. Truncated streams are common in the
+// wild, so whatever was decoded before the error is kept.
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		// Some writers omit the zlib header
+		out, rawErr := readLimited(flate.NewReader(bytes.NewReader(data)))
+		if errors.Is(rawErr, errStreamTooLarge) {
+			return nil, rawErr
+		}
+		if rawErr != nil && len(out) == 0 {
+			return nil, err
+		}
+		return out, nil
+	}
+	defer zr.Close()
+
+	out, err := readLimited(zr)
+	if errors.Is(err, errStreamTooLarge) || (err != nil && len(out) == 0) {
+		return nil, err
+	}
+	return out, nil
+}
+
+// readLimited reads a decompressed stream, failing once it exceeds maxStreamSize
+func readLimited(r io.Reader) ([]byte, error) {
+	out, err := io.ReadAll(io.LimitReader(r, maxStreamSize+1))
+	if len(out) > maxStreamSize {
+		return nil, errStreamTooLarge
+	}
+	return out, err
+}
+
+// unpredict reverses PNG predictors applied before compression
+func unpredict(data []byte, params dict) ([]byte, error) {
+	predictor, _ := params["Predictor"].(int)
+	if predictor < 10 {
+		return data, nil
+	}
+
+	columns := intOr(params["Columns"], 1)
+	colors := intOr(params["Colors"], 1)
+	bpc := intOr(params["BitsPerComponent"], 8)
+
+	bpp := (colors*bpc + 7) / 8
+	rowLen := (columns*colors*bpc + 7) / 8
+	if rowLen <= 0 {
+		return nil, fmt.Errorf("invalid predictor columns")
+	}
+
+	out := make([]byte, 0, len(data))
+	prev := make([]byte, rowLen)
+	for pos := 0; pos+1+rowLen <= len(data); pos += rowLen + 1 {
+		kind := data[pos]
+		row := append([]byte(nil), data[pos+1:pos+1+rowLen]...)
+
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left = row[i-bpp]
+				upLeft = prev[i-bpp]
+			}
+			up := prev[i]
+
+			switch kind {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+
+		out = append(out, row...)
+		prev = row
+	}
+
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func intOr(obj object, fallback int) int {
+	if v, ok := obj.(int); ok {
+		return v
+	}
+	return fallback
+}
+
+func decodeASCIIHex(data []byte) ([]byte, error) {
+	var digits []byte
+	for _, c := range data {
+		if c == '>' {
+			break
+		}
+		if !isSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, len(digits)/2)
+	if _, err := hex.Decode(out, digits); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if idx := bytes.Index(data, []byte("~>")); idx >= 0 {
+		data = data[:idx]
+	}
+
+	out := make([]byte, 4*len(data)/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, err
+	}
+	return out[:n], nil
+}
@@ -0,0 +1,281 @@
+package pdf
+
+import (
+	"bytes"
+	"strings"
+)
+
+// maxFormDepth bounds nesting of form XObjects
+const maxFormDepth = 8
+
+// wordGap is the TJ adjustment, in thousandths of an em, treated as a space
+const wordGap = 200
+
+// textExtractor interprets content streams and collects the text they draw
+type textExtractor struct {
+	r      *reader
+	sb     strings.Builder
+	images []Image
+	lineY  float64
+	hasY   bool
+}
+
+// pageText returns the text of a page and the images it draws
+func (r *reader) pageText(page dict) (string, []Image) {
+	te := &textExtractor{r: r}
+	te.run(r.pageContent(page), r.resolveDict(page["Resources"]), 0)
+	return cleanText(te.sb.String()), te.images
+}
+
+// pageContent concatenates the decoded content streams of a page
+func (r *reader) pageContent(page dict) []byte {
+	var parts []object
+	switch c := page["Contents"].(type) {
+	case array:
+		parts = c
+	default:
+		if arr, ok := r.resolve(c).(array); ok {
+			parts = arr
+		} else {
+			parts = array{c}
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, part := range parts {
+		s, ok := r.resolve(part).(stream)
+		if !ok {
+			continue
+		}
+		data, err := r.decodeStream(s)
+		if err != nil {
+			continue
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+func (te *textExtractor) run(content []byte, resources dict, depth int) {
+	fonts := make(map[name]*font)
+	var current *font
+	var operands []object
+
+	l := newLexer(content)
+	for {
+		l.skipSpace()
+		if l.eof() {
+			return
+		}
+
+		obj, err := l.readObject()
+		if err != nil {
+			return
+		}
+
+		op, isOp := obj.(keyword)
+		if !isOp {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "BI":
+			te.images = append(te.images, Image{})
+			skipInlineImage(l)
+
+		case "Tf":
+			if len(operands) >= 1 {
+				if fontName, ok := operands[0].(name); ok {
+					current = te.font(fonts, resources, fontName)
+				}
+			}
+
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				tx, ty := toFloat(operands[0]), toFloat(operands[1])
+				te.lineY += ty
+				if ty != 0 {
+					te.newline()
+				} else if tx != 0 {
+					te.space()
+				}
+			}
+
+		case "Tm":
+			if len(operands) >= 6 {
+				y := toFloat(operands[5])
+				if te.hasY && y != te.lineY {
+					te.newline()
+				} else {
+					te.space()
+				}
+				te.lineY, te.hasY = y, true
+			}
+
+		case "T*":
+			te.newline()
+
+		case "Tj":
+			if len(operands) >= 1 {
+				te.show(current, operands[0])
+			}
+
+		case "'":
+			te.newline()
+			if len(operands) >= 1 {
+				te.show(current, operands[0])
+			}
+
+		case "\"":
+			te.newline()
+			if len(operands) >= 3 {
+				te.show(current, operands[2])
+			}
+
+		case "TJ":
+			if len(operands) >= 1 {
+				if items, ok := operands[0].(array); ok {
+					for _, item := range items {
+						if _, isStr := item.(string); isStr {
+							te.show(current, item)
+						} else if toFloat(item) < -wordGap {
+							te.space()
+						}
+					}
+				}
+			}
+
+		case "Do":
+			if len(operands) >= 1 {
+				if xName, ok := operands[0].(name); ok {
+					te.drawXObject(resources, xName, depth)
+				}
+			}
+		}
+
+		operands = operands[:0]
+	}
+}
+
+// drawXObject collects image XObjects and extracts text from form XObjects
+func (te *textExtractor) drawXObject(resources dict, xName name, depth int) {
+	xobjects := te.r.resolveDict(resources["XObject"])
+	s, ok := te.r.resolve(xobjects[xName]).(stream)
+	if !ok {
+		return
+	}
+
+	switch s.hdr["Subtype"] {
+	case name("Image"):
+		te.images = append(te.images, Image{r: te.r, s: &s})
+	case name("Form"):
+		if depth >= maxFormDepth {
+			return
+		}
+		data, err := te.r.decodeStream(s)
+		if err != nil {
+			return
+		}
+		formResources := te.r.resolveDict(s.hdr["Resources"])
+		if formResources == nil {
+			formResources = resources
+		}
+		te.run(data, formResources, depth+1)
+	}
+}
+
+func (te *textExtractor) font(cache map[name]*font, resources dict, fontName name) *font {
+	if f, ok := cache[fontName]; ok {
+		return f
+	}
+	fonts := te.r.resolveDict(resources["Font"])
+	f := te.r.loadFont(te.r.resolveDict(fonts[fontName]))
+	cache[fontName] = f
+	return f
+}
+
+func (te *textExtractor) show(f *font, obj object) {
+	s, ok := obj.(string)
+	if !ok {
+		return
+	}
+	if f == nil {
+		f = defaultFont
+	}
+	te.sb.WriteString(f.decode(s))
+}
+
+func (te *textExtractor) newline() {
+	text := te.sb.String()
+	if len(text) > 0 && text[len(text)-1] != '\n' {
+		te.sb.WriteByte('\n')
+	}
+}
+
+func (te *textExtractor) space() {
+	text := te.sb.String()
+	if len(text) > 0 {
+		last := text[len(text)-1]
+		if last != ' ' && last != '\n' {
+			te.sb.WriteByte(' ')
+		}
+	}
+}
+
+// skipInlineImage moves past the binary data of an inline image (BI ... ID data EI)
+func skipInlineImage(l *lexer) {
+	idx := bytes.Index(l.data[l.pos:], []byte("ID"))
+	if idx < 0 {
+		l.pos = len(l.data)
+		return
+	}
+	pos := l.pos + idx + len("ID") + 1
+
+	for pos+2 <= len(l.data) {
+		idx := bytes.Index(l.data[pos:], []byte("EI"))
+		if idx < 0 {
+			break
+		}
+		at := pos + idx
+		before := at == 0 || isSpace(l.data[at-1])
+		after := at+2 == len(l.data) || isSpace(l.data[at+2])
+		if before && after {
+			l.pos = at + 2
+			return
+		}
+		pos = at + 2
+	}
+	l.pos = len(l.data)
+}
+
+func toFloat(obj object) float64 {
+	switch v := obj.(type) {
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// cleanText trims trailing spaces and collapses runs of blank lines
+func cleanText(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	blank := 0
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
@@ -0,0 +1,58 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+
+	"todo-agent-backend/internal/models"
+	"todo-agent-backend/pkg/retry"
+)
+
+// FollowUp is a repair round: the rejected reply and the instructions
+// asking the model to correct it
+type FollowUp struct {
+	Reply  string
+	Repair string
+}
+
+// SendFunc sends the conversation to the model and returns its reply.
+// followUp is nil for the first request. Otherwise the caller appends
+// it to the conversation before sending.
+type SendFunc func(ctx context.Context, followUp *FollowUp) (string, error)
+
+// Extraction is the parse and repair loop shared by the model clients
+type Extraction struct {
+	Prompt          Prompt
+	MaxRepairRounds int
+	Repairs         *retry.Counter // counts repair rounds, may be nil
+}
+
+// Run sends the request and parses the todo list from the reply. A reply
+// that is not a valid todo list is sent back with the error for
+// correction, up to MaxRepairRounds times.
+func (e Extraction) Run(ctx context.Context, send SendFunc) ([]models.TodoItem, error) {
+	var followUp *FollowUp
+
+	for round := 0; ; round++ {
+		reply, err := send(ctx, followUp)
+		if err != nil {
+			return nil, err
+		}
+
+		todos, parseErr := ParseTodos(reply)
+		if parseErr == nil {
+			return todos, nil
+		}
+		if round >= e.MaxRepairRounds {
+			return nil, fmt.Errorf("failed to parse todos from response: %w", parseErr)
+		}
+
+		repair, err := e.Prompt.ForRepair(parseErr)
+		if err != nil {
+			return nil, err
+		}
+
+		e.Repairs.Add(1)
+		followUp = &FollowUp{Reply: reply, Repair: repair}
+	}
+}
@@ -0,0 +1,44 @@
+package prompt
+
+import (
+	"context"
+	"testing"
+
+	"todo-agent-backend/pkg/retry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtraction_Run(t *testing.T) {
+	replies := []string{`[{"title": ""}]`, `[{"title": "Kirim laporan"}]`}
+	var followUps []*FollowUp
+
+	repairs := &retry.Counter{}
+	extraction := Extraction{MaxRepairRounds: 1, Repairs: repairs}
+
+	todos, err := extraction.Run(context.Background(), func(ctx context.Context, followUp *FollowUp) (string, error) {
+		followUps = append(followUps, followUp)
+		return replies[len(followUps)-1], nil
+	})
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "Kirim laporan", todos[0].Title)
+	assert.Equal(t, 1, repairs.Attempts())
+
+	// The second request carries the rejected reply and the repair instructions
+	require.Len(t, followUps, 2)
+	assert.Nil(t, followUps[0])
+	assert.Equal(t, replies[0], followUps[1].Reply)
+	assert.Contains(t, followUps[1].Repair, "title is required")
+}
+
+func TestExtraction_RunBudgetExhausted(t *testing.T) {
+	calls := 0
+	_, err := Extraction{}.Run(context.Background(), func(ctx context.Context, followUp *FollowUp) (string, error) {
+		calls++
+		return "not json", nil
+	})
+	assert.ErrorContains(t, err, "failed to parse todos from response")
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,261 @@
+package prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"todo-agent-backend/internal/models"
+)
+
+// ErrInvalidResponse is returned when a model reply is not a valid todo list
+var ErrInvalidResponse = errors.New("invalid todo response")
+
+// MaxSubtaskDepth is how many levels of subtasks a todo may have
+const MaxSubtaskDepth = 2
+
+// rawTodo mirrors the reply format before validation
+type rawTodo struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	DueDate     *string `json:"due_date"`
+	DueTime     *string `json:"due_time"`
+
+	Priority         *string  `json:"priority"`
+	Tags             []string `json:"tags"`
+	EstimatedMinutes *float64 `json:"estimated_minutes"`
+	Assignee         *string  `json:"assignee"`
+
+	SourceQuote *string   `json:"source_quote"`
+	Subtasks    []rawTodo `json:"subtasks"`
+
+	source *models.SourceSpan // kept as is when validating edited todos
+}
+
+// ParseTodos reads the todo list from a model reply. It tolerates code
+// fences and surrounding prose by falling back to the first JSON array in
+// the text, and validates every item. Errors wrap ErrInvalidResponse.
+func ParseTodos(text string) ([]models.TodoItem, error) {
+	var raw []rawTodo
+	if err := json.Unmarshal([]byte(stripCodeFence(text)), &raw); err != nil {
+		array, found := firstJSONArray(text)
+		if !found {
+			return nil, fmt.Errorf("%w: no JSON array found", ErrInvalidResponse)
+		}
+		if err := json.Unmarshal([]byte(array), &raw); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+		}
+	}
+
+	todos := make([]models.TodoItem, 0, len(raw))
+	for i, item := range raw {
+		todo, err := validateTodo(item, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%w: item %d: %v", ErrInvalidResponse, i+1, err)
+		}
+		todos = append(todos, todo)
+	}
+
+	return todos, nil
+}
+
+// ValidateTodos checks todos edited by a user against the rules model
+// replies follow and returns them normalized
+func ValidateTodos(items []models.TodoItem) ([]models.TodoItem, error) {
+	todos := make([]models.TodoItem, 0, len(items))
+	for i, item := range items {
+		todo, err := validateTodo(toRawTodo(item), 0)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i+1, err)
+		}
+		todos = append(todos, todo)
+	}
+	return todos, nil
+}
+
+// toRawTodo converts an item back to the reply format
+func toRawTodo(item models.TodoItem) rawTodo {
+	raw := rawTodo{
+		Title:       &item.Title,
+		Description: &item.Description,
+		DueDate:     item.DueDate,
+		DueTime:     item.DueTime,
+		Priority:    &item.Priority,
+		Tags:        item.Tags,
+		Assignee:    &item.Assignee,
+		source:      item.Source,
+	}
+
+	if item.EstimatedMinutes != nil {
+		minutes := float64(*item.EstimatedMinutes)
+		raw.EstimatedMinutes = &minutes
+	}
+
+	for _, subtask := range item.Subtasks {
+		raw.Subtasks = append(raw.Subtasks, toRawTodo(subtask))
+	}
+
+	return raw
+}
+
+// validateTodo checks a single item and its subtasks against the reply schema
+func validateTodo(item rawTodo, depth int) (models.TodoItem, error) {
+	if item.Title == nil || strings.TrimSpace(*item.Title) == "" {
+		return models.TodoItem{}, errors.New("title is required")
+	}
+
+	todo := models.TodoItem{Title: strings.TrimSpace(*item.Title)}
+
+	if item.Description != nil {
+		todo.Description = strings.TrimSpace(*item.Description)
+	}
+
+	if item.DueDate != nil {
+		dueDate := strings.TrimSpace(*item.DueDate)
+		if dueDate != "" && dueDate != "null" {
+			if _, err := time.Parse("2006-01-02", dueDate); err != nil {
+				return models.TodoItem{}, fmt.Errorf("due_date %q is not a YYYY-MM-DD date", dueDate)
+			}
+			todo.DueDate = &dueDate
+		}
+	}
+
+	if item.DueTime != nil {
+		dueTime := strings.TrimSpace(*item.DueTime)
+		if dueTime != "" && dueTime != "null" {
+			at, err := time.Parse(time.RFC3339, dueTime)
+			if err != nil {
+				return models.TodoItem{}, fmt.Errorf("due_time %q is not an RFC 3339 timestamp", dueTime)
+			}
+			todo.DueTime = &dueTime
+
+			// The calendar date in the offset the model gave
+			if todo.DueDate == nil {
+				dueDate := at.Format("2006-01-02")
+				todo.DueDate = &dueDate
+			}
+		}
+	}
+
+	if item.Priority != nil {
+		priority := strings.ToLower(strings.TrimSpace(*item.Priority))
+		switch priority {
+		case "", "null":
+		case models.PriorityLow, models.PriorityMedium, models.PriorityHigh:
+			todo.Priority = priority
+		default:
+			return models.TodoItem{}, fmt.Errorf("priority %q must be low, medium, high or null", *item.Priority)
+		}
+	}
+
+	todo.Tags = normalizeTags(item.Tags)
+
+	if item.EstimatedMinutes != nil {
+		minutes := int(math.Round(*item.EstimatedMinutes))
+		if minutes <= 0 {
+			return models.TodoItem{}, fmt.Errorf("estimated_minutes must be a positive number of minutes")
+		}
+		todo.EstimatedMinutes = &minutes
+	}
+
+	if item.Assignee != nil {
+		todo.Assignee = strings.TrimSpace(*item.Assignee)
+	}
+
+	if item.source != nil {
+		todo.Source = item.source
+	} else if item.SourceQuote != nil {
+		if quote := strings.TrimSpace(*item.SourceQuote); quote != "" && quote != "null" {
+			todo.Source = &models.SourceSpan{Text: quote}
+		}
+	}
+
+	if len(item.Subtasks) > 0 && depth >= MaxSubtaskDepth {
+		return models.TodoItem{}, fmt.Errorf("subtasks may be nested at most %d levels deep", MaxSubtaskDepth)
+	}
+	for i, subtask := range item.Subtasks {
+		child, err := validateTodo(subtask, depth+1)
+		if err != nil {
+			return models.TodoItem{}, fmt.Errorf("subtask %d: %w", i+1, err)
+		}
+		todo.Subtasks = append(todo.Subtasks, child)
+	}
+
+	return todo, nil
+}
+
+// normalizeTags lowercases tags and drops blanks and duplicates
+func normalizeTags(tags []string) []string {
+	var out []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+// stripCodeFence removes a surrounding ``` or ```json fence
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+
+	text = strings.TrimPrefix(text, "```")
+	if newline := strings.IndexByte(text, '\n'); newline >= 0 {
+		text = text[newline+1:]
+	}
+	text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+
+	return strings.TrimSpace(text)
+}
+
+// firstJSONArray returns the first balanced [...] in text, skipping
+// brackets that appear inside JSON strings
+func firstJSONArray(text string) (string, bool) {
+	start := strings.IndexByte(text, '[')
+	for start >= 0 {
+		depth := 0
+		inString, escaped := false, false
+
+		for i := start; i < len(text); i++ {
+			c := text[i]
+			switch {
+			case escaped:
+				escaped = false
+			case inString && c == '\\':
+				escaped = true
+			case c == '"':
+				inString = !inString
+			case inString:
+			case c == '[':
+				depth++
+			case c == ']':
+				depth--
+				if depth == 0 {
+					candidate := text[start : i+1]
+					if json.Valid([]byte(candidate)) {
+						return candidate, true
+					}
+					i = len(text)
+				}
+			}
+		}
+
+		next := strings.IndexByte(text[start+1:], '[')
+		if next < 0 {
+			break
+		}
+		start += next + 1
+	}
+
+	return "", false
+}
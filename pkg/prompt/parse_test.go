@@ -0,0 +1,128 @@
+package prompt
+
+import (
+	"testing"
+
+	"todo-agent-backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTodos(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+	}{
+		{name: "plain", reply: `[{"title":"Kirim laporan","description":"","due_date":"2025-07-18"}]`},
+		{name: "json fence", reply: "```json\n[{\"title\":\"Kirim laporan\",\"due_date\":\"2025-07-18\"}]\n```"},
+		{name: "bare fence", reply: "```\n[{\"title\":\"Kirim laporan\",\"due_date\":\"2025-07-18\"}]\n```"},
+		{name: "surrounding prose", reply: "Berikut daftar todo [hasil]:\n[{\"title\":\"Kirim laporan\",\"description\":null,\"due_date\":\"2025-07-18\"}]\nSemoga membantu!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			todos, err := ParseTodos(tt.reply)
+			require.NoError(t, err)
+			require.Len(t, todos, 1)
+			assert.Equal(t, "Kirim laporan", todos[0].Title)
+			assert.Equal(t, "", todos[0].Description)
+			require.NotNil(t, todos[0].DueDate)
+			assert.Equal(t, "2025-07-18", *todos[0].DueDate)
+		})
+	}
+}
+
+func TestParseTodos_BracketsInStrings(t *testing.T) {
+	todos, err := ParseTodos(`Hasil: [{"title":"Review [draft] ]PR","due_date":""}] selesai`)
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "Review [draft] ]PR", todos[0].Title)
+	assert.Nil(t, todos[0].DueDate)
+}
+
+func TestParseTodos_DueTime(t *testing.T) {
+	todos, err := ParseTodos(`[{"title":"Rapat","due_date":null,"due_time":"2025-07-18T00:30:00+07:00"}]`)
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	require.NotNil(t, todos[0].DueTime)
+	assert.Equal(t, "2025-07-18T00:30:00+07:00", *todos[0].DueTime)
+
+	// The date comes from the local time, not UTC
+	require.NotNil(t, todos[0].DueDate)
+	assert.Equal(t, "2025-07-18", *todos[0].DueDate)
+}
+
+func TestParseTodos_Details(t *testing.T) {
+	todos, err := ParseTodos(`[{"title":"Kirim laporan","priority":"High","tags":["#Finance"," q3 ","finance",""],"estimated_minutes":44.6,"assignee":" Budi "}]`)
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "high", todos[0].Priority)
+	assert.Equal(t, []string{"finance", "q3"}, todos[0].Tags)
+	require.NotNil(t, todos[0].EstimatedMinutes)
+	assert.Equal(t, 45, *todos[0].EstimatedMinutes)
+	assert.Equal(t, "Budi", todos[0].Assignee)
+}
+
+func TestParseTodos_Subtasks(t *testing.T) {
+	todos, err := ParseTodos(`[{"title":"Siapkan rapat","subtasks":[{"title":"Pesan ruangan"},{"title":"Kirim undangan","subtasks":[{"title":"Tim finance"}]}]}]`)
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	require.Len(t, todos[0].Subtasks, 2)
+	assert.Equal(t, "Pesan ruangan", todos[0].Subtasks[0].Title)
+	require.Len(t, todos[0].Subtasks[1].Subtasks, 1)
+	assert.Equal(t, "Tim finance", todos[0].Subtasks[1].Subtasks[0].Title)
+}
+
+func TestParseTodos_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		message string
+	}{
+		{name: "no array", reply: "Tidak ada tugas.", message: "no JSON array found"},
+		{name: "missing title", reply: `[{"title":"Ok"},{"description":"x"}]`, message: "item 2: title is required"},
+		{name: "bad date", reply: `[{"title":"Ok","due_date":"besok"}]`, message: `item 1: due_date "besok" is not a YYYY-MM-DD date`},
+		{name: "bad time", reply: `[{"title":"Ok","due_time":"15:00"}]`, message: `item 1: due_time "15:00" is not an RFC 3339 timestamp`},
+		{name: "bad priority", reply: `[{"title":"Ok","priority":"penting"}]`, message: `item 1: priority "penting" must be low, medium, high or null`},
+		{name: "bad estimate", reply: `[{"title":"Ok","estimated_minutes":0}]`, message: "item 1: estimated_minutes must be a positive number of minutes"},
+		{name: "bad subtask", reply: `[{"title":"Ok","subtasks":[{"title":"a"},{"title":" "}]}]`, message: "item 1: subtask 2: title is required"},
+		{name: "too deep", reply: `[{"title":"a","subtasks":[{"title":"b","subtasks":[{"title":"c","subtasks":[{"title":"d"}]}]}]}]`, message: "item 1: subtask 1: subtask 1: subtasks may be nested at most 2 levels deep"},
+		{name: "wrong type", reply: `[{"title":42}]`, message: "cannot unmarshal number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTodos(tt.reply)
+			assert.ErrorIs(t, err, ErrInvalidResponse)
+			assert.ErrorContains(t, err, tt.message)
+		})
+	}
+}
+
+func TestValidateTodos(t *testing.T) {
+	minutes := 30
+	todos, err := ValidateTodos([]models.TodoItem{{
+		Title:            "  Kirim laporan ",
+		Priority:         "HIGH",
+		Tags:             []string{"#Kantor", "kantor"},
+		EstimatedMinutes: &minutes,
+		Source:           &models.SourceSpan{Text: "kirim laporan", Page: 2},
+		Subtasks:         []models.TodoItem{{Title: "Cek angka"}},
+	}})
+	require.NoError(t, err)
+	require.Len(t, todos, 1)
+	assert.Equal(t, "Kirim laporan", todos[0].Title)
+	assert.Equal(t, "high", todos[0].Priority)
+	assert.Equal(t, []string{"kantor"}, todos[0].Tags)
+	assert.Equal(t, 30, *todos[0].EstimatedMinutes)
+	assert.Equal(t, 2, todos[0].Source.Page)
+	require.Len(t, todos[0].Subtasks, 1)
+
+	dueDate := "18/07/2025"
+	_, err = ValidateTodos([]models.TodoItem{{Title: "Kirim laporan"}, {Title: "Beli kopi", DueDate: &dueDate}})
+	assert.EqualError(t, err, `item 2: due_date "18/07/2025" is not a YYYY-MM-DD date`)
+
+	_, err = ValidateTodos([]models.TodoItem{{Title: " "}})
+	assert.EqualError(t, err, "item 1: title is required")
+}
@@ -0,0 +1,62 @@
+// Package prompt builds the instructions sent to language models to extract
+// todos and parses the todo lists they reply with
+package prompt
+
+import (
+	"time"
+)
+
+// Prompt is a template bound to the context of one job
+type Prompt struct {
+	Template *Template
+	Timezone string    // IANA name, UTC when empty
+	Now      time.Time // reference time, the current time when zero
+}
+
+// ForText returns the prompt for extracting todos from text
+func (p Prompt) ForText(text string) (string, error) {
+	data := p.data()
+	data.Text = text
+	return p.template().render("text", data)
+}
+
+// ForImage returns the prompt sent along with an image
+func (p Prompt) ForImage() (string, error) {
+	return p.template().render("image", p.data())
+}
+
+// ForRepair asks the model to correct a reply that failed parsing or validation
+func (p Prompt) ForRepair(err error) (string, error) {
+	data := p.data()
+	data.Error = err.Error()
+	return p.template().render("repair", data)
+}
+
+func (p Prompt) template() *Template {
+	if p.Template == nil {
+		return Default()
+	}
+	return p.Template
+}
+
+func (p Prompt) data() Data {
+	loc := time.UTC
+	if p.Timezone != "" {
+		if l, err := time.LoadLocation(p.Timezone); err == nil {
+			loc = l
+		}
+	}
+
+	now := p.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	return Data{
+		Timezone: loc.String(),
+		Today:    now.In(loc).Format("2006-01-02"),
+		Weekday:  now.In(loc).Weekday().String(),
+		Now:      now.In(loc).Format(time.RFC3339),
+		Locale:   p.template().Locale,
+	}
+}
@@ -0,0 +1,194 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// ErrUnknownLanguage is returned for a language without a template
+var ErrUnknownLanguage = errors.New("no prompt template for language")
+
+// sections every template must define
+var sections = []string{"text", "image", "repair"}
+
+// Meta identifies a template. Name and Version are stored on job results
+// so every extraction can be traced to the prompt that produced it.
+type Meta struct {
+	Name     string
+	Version  string
+	Language string
+	Locale   string
+}
+
+// Data holds the values available to templates
+type Data struct {
+	Text     string // input text, empty for images
+	Timezone string // IANA name of the user's timezone
+	Today    string // current date in the user's timezone, YYYY-MM-DD
+	Weekday  string // current weekday in the user's timezone, in English
+	Now      string // current time in the user's timezone, RFC 3339
+	Locale   string
+	Error    string // why the previous reply was rejected, repair only
+}
+
+// Template is a parsed prompt template. The source defines the sections
+// "text", "image" and "repair" with {{define}}; each is rendered with Data.
+type Template struct {
+	Meta
+	tmpl *template.Template
+}
+
+// Parse parses template source and checks that every section is defined
+func Parse(meta Meta, source string) (*Template, error) {
+	tmpl, err := template.New(meta.Name).Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt template %s: %w", meta.Name, err)
+	}
+
+	for _, section := range sections {
+		if tmpl.Lookup(section) == nil {
+			return nil, fmt.Errorf("prompt template %s does not define %q", meta.Name, section)
+		}
+	}
+
+	return &Template{Meta: meta, tmpl: tmpl}, nil
+}
+
+// Load reads and parses the template file at path
+func Load(meta Meta, path string) (*Template, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return Parse(meta, string(source))
+}
+
+func (t *Template) render(section string, data Data) (string, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.ExecuteTemplate(&buf, section, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s/%s: %w", t.Name, section, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Registry holds one template per language
+type Registry struct {
+	templates       map[string]*Template
+	defaultLanguage string
+}
+
+// NewRegistry creates a registry. The default language is used when a job
+// does not ask for one and must have a template.
+func NewRegistry(defaultLanguage string, templates ...*Template) (*Registry, error) {
+	r := &Registry{
+		templates:       make(map[string]*Template, len(templates)),
+		defaultLanguage: strings.ToLower(defaultLanguage),
+	}
+
+	for _, t := range templates {
+		language := strings.ToLower(t.Language)
+		if _, exists := r.templates[language]; exists {
+			return nil, fmt.Errorf("duplicate prompt template for language %s", language)
+		}
+		r.templates[language] = t
+	}
+
+	if _, ok := r.templates[r.defaultLanguage]; !ok {
+		return nil, fmt.Errorf("%w: %s (default)", ErrUnknownLanguage, defaultLanguage)
+	}
+
+	return r, nil
+}
+
+// DefaultRegistry returns a registry holding only the built-in template
+func DefaultRegistry() *Registry {
+	r, _ := NewRegistry(Default().Language, Default())
+	return r
+}
+
+// Get returns the template for language, or the default when language is empty
+func (r *Registry) Get(language string) (*Template, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		language = r.defaultLanguage
+	}
+
+	t, ok := r.templates[language]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownLanguage, language)
+	}
+	return t, nil
+}
+
+// Languages returns the supported languages in sorted order
+func (r *Registry) Languages() []string {
+	languages := make([]string, 0, len(r.templates))
+	for language := range r.templates {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultTemplate *Template
+)
+
+// Default returns the built-in Indonesian template, used when no
+// templates are configured
+func Default() *Template {
+	defaultOnce.Do(func() {
+		t, err := Parse(Meta{
+			Name:     "builtin-id",
+			Version:  "5",
+			Language: "id",
+			Locale:   "id-ID",
+		}, builtinSource)
+		if err != nil {
+			panic(err)
+		}
+		defaultTemplate = t
+	})
+	return defaultTemplate
+}
+
+const builtinSource = `{{define "format"}}[{"title":"...","description":"...","due_date":"YYYY-MM-DD|null","due_time":"RFC 3339|null","priority":"low|medium|high|null","tags":["..."],"estimated_minutes":30,"assignee":"...|null","source_quote":"...","subtasks":[{"title":"...", ...}]}]
+
+ATURAN:
+1. Ekstrak hanya tugas/aktivitas yang perlu dilakukan
+2. Jangan termasuk hal yang sudah selesai
+3. due_date harus format YYYY-MM-DD atau null jika tidak ada tanggal
+4. due_time hanya diisi jika ada jam yang jelas, format RFC 3339 dengan offset zona waktu {{.Timezone}} (contoh: {{.Now}}); selain itu null
+5. description boleh kosong jika tidak ada detail
+6. priority hanya low, medium atau high sesuai tingkat urgensi yang tersirat (misalnya "penting", "segera", "ASAP"); null jika tidak jelas
+7. tags berisi beberapa label singkat huruf kecil seperti nama proyek atau kategori; array kosong jika tidak ada
+8. estimated_minutes diisi perkiraan durasi dalam menit hanya jika disebutkan atau jelas dari teks; selain itu null
+9. assignee diisi nama orang yang bertanggung jawab jika disebutkan (misalnya "Budi kirim laporan" berarti assignee "Budi"); selain itu null
+10. Langkah-langkah di bawah satu tugas (misalnya sub-poin atau checklist) dimasukkan ke subtasks tugas tersebut dengan format yang sama, paling dalam 2 tingkat; bukan sebagai todo terpisah
+11. source_quote berisi kutipan persis (disalin apa adanya, tanpa diterjemahkan) dari bagian input yang menjadi sumber todo
+12. Response harus valid JSON array
+13. Sekarang {{.Now}} ({{.Weekday}}, zona waktu {{.Timezone}}); hitung tanggal relatif seperti "besok" atau "Jumat depan" dari hari ini ({{.Today}})
+14. Tulis title dan description dalam Bahasa Indonesia{{end}}
+
+{{define "text"}}Anda adalah asisten produktivitas. Dari teks berikut, ekstrak daftar todo dalam format JSON:
+{{template "format" .}}
+
+Teks:
+---
+{{.Text}}{{end}}
+
+{{define "image"}}Anda adalah asisten produktivitas. Gambar terlampir berisi catatan, bisa berupa tulisan tangan. Baca isinya lalu ekstrak daftar todo dalam format JSON:
+{{template "format" .}}{{end}}
+
+{{define "repair"}}Jawaban sebelumnya bukan daftar todo yang valid: {{.Error}}
+
+Perbaiki jawaban tersebut. Kirim ulang HANYA JSON array tanpa teks lain, dengan format:
+{{template "format" .}}{{end}}
+`
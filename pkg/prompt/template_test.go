@@ -0,0 +1,86 @@
+package prompt
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_ShippedTemplates(t *testing.T) {
+	for _, language := range []string{"id", "en"} {
+		t.Run(language, func(t *testing.T) {
+			tmpl, err := Load(Meta{Name: "todo-" + language, Language: language}, "../../prompts/todo_"+language+".tmpl")
+			require.NoError(t, err)
+
+			p := Prompt{Template: tmpl}
+			_, err = p.ForText("Kirim laporan")
+			assert.NoError(t, err)
+			_, err = p.ForImage()
+			assert.NoError(t, err)
+			_, err = p.ForRepair(errors.New("title is required"))
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParse_MissingSection(t *testing.T) {
+	_, err := Parse(Meta{Name: "broken"}, `{{define "text"}}{{.Text}}{{end}}`)
+	assert.ErrorContains(t, err, `does not define "image"`)
+}
+
+func TestPrompt_RendersJobContext(t *testing.T) {
+	tmpl, err := Parse(Meta{Name: "test", Locale: "en-US"}, `
+{{define "text"}}{{.Today}} {{.Weekday}} {{.Timezone}} {{.Locale}}: {{.Text}}{{end}}
+{{define "image"}}image{{end}}
+{{define "repair"}}fix: {{.Error}}{{end}}`)
+	require.NoError(t, err)
+
+	// 23:30 UTC is already the next day in Jakarta
+	p := Prompt{
+		Template: tmpl,
+		Timezone: "Asia/Jakarta",
+		Now:      time.Date(2025, 7, 15, 23, 30, 0, 0, time.UTC),
+	}
+
+	text, err := p.ForText("Send report")
+	require.NoError(t, err)
+	assert.Equal(t, "2025-07-16 Wednesday Asia/Jakarta en-US: Send report", text)
+
+	repair, err := p.ForRepair(errors.New("item 0: title is required"))
+	require.NoError(t, err)
+	assert.Equal(t, "fix: item 0: title is required", repair)
+}
+
+func TestPrompt_DefaultTemplate(t *testing.T) {
+	text, err := Prompt{}.ForText("Kirim laporan")
+	require.NoError(t, err)
+	assert.Contains(t, text, "Anda adalah asisten produktivitas")
+	assert.Contains(t, text, "zona waktu UTC")
+	assert.Contains(t, text, "Kirim laporan")
+}
+
+func TestRegistry(t *testing.T) {
+	id := &Template{Meta: Meta{Name: "id", Language: "id"}}
+	en := &Template{Meta: Meta{Name: "en", Language: "en"}}
+
+	r, err := NewRegistry("id", id, en)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"en", "id"}, r.Languages())
+
+	got, err := r.Get("")
+	require.NoError(t, err)
+	assert.Same(t, id, got)
+
+	got, err = r.Get("EN")
+	require.NoError(t, err)
+	assert.Same(t, en, got)
+
+	_, err = r.Get("fr")
+	assert.ErrorIs(t, err, ErrUnknownLanguage)
+
+	_, err = NewRegistry("fr", id, en)
+	assert.ErrorIs(t, err, ErrUnknownLanguage)
+}
@@ -0,0 +1,178 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultBaseDelay = 500 * time.Millisecond
+	DefaultMaxDelay  = 10 * time.Second
+)
+
+// sleep waits for d or until ctx is done. It is replaced in tests.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Policy describes how failed calls are retried
+type Policy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// NewPolicy creates a policy with exponential backoff and the default delays
+func NewPolicy(maxRetries int) Policy {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	return Policy{
+		MaxRetries: maxRetries,
+		BaseDelay:  DefaultBaseDelay,
+		MaxDelay:   DefaultMaxDelay,
+	}
+}
+
+// HTTPError is returned for unexpected HTTP response statuses
+type HTTPError struct {
+	Service    string
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.Service, e.StatusCode)
+}
+
+// NewHTTPError builds an HTTPError from a response
+func NewHTTPError(service string, resp *http.Response) *HTTPError {
+	return &HTTPError{
+		Service:    service,
+		StatusCode: resp.StatusCode,
+		RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// Counter counts attempts across calls. It is safe for concurrent use
+// and a nil Counter discards counts.
+type Counter struct {
+	attempts atomic.Int64
+}
+
+// Add records n attempts
+func (c *Counter) Add(n int) {
+	if c != nil {
+		c.attempts.Add(int64(n))
+	}
+}
+
+// Attempts returns the number of recorded attempts
+func (c *Counter) Attempts() int {
+	if c == nil {
+		return 0
+	}
+	return int(c.attempts.Load())
+}
+
+// Do calls fn until it succeeds, fails with a non-retryable error or the
+// retry budget is spent. fn receives the 1-based attempt number. Waiting
+// between attempts stops as soon as ctx is done.
+func (p Policy) Do(ctx context.Context, fn func(attempt int) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(attempt)
+		if err == nil || attempt > p.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		if err := sleep(ctx, p.delay(attempt, err)); err != nil {
+			return err
+		}
+	}
+}
+
+// delay returns how long to wait before the next attempt. A Retry-After
+// hint from the server wins over the computed backoff.
+func (p Policy) delay(attempt int, err error) time.Duration {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 {
+		return httpErr.RetryAfter
+	}
+
+	backoff := p.BaseDelay << (attempt - 1)
+	if backoff <= 0 || backoff > p.MaxDelay {
+		backoff = p.MaxDelay
+	}
+
+	// Equal jitter: wait at least half of the backoff
+	half := backoff / 2
+	if half <= 0 {
+		return backoff
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+// IsRetryable reports whether err is worth retrying: network errors,
+// 429 Too Many Requests and 5xx responses. Other 4xx responses,
+// cancellation and decoding errors are permanent.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// ParseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+
+	return 0
+}
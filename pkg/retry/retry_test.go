@@ -0,0 +1,146 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withRecordedSleeps(t *testing.T) *[]time.Duration {
+	var slept []time.Duration
+	original := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	t.Cleanup(func() { sleep = original })
+	return &slept
+}
+
+func TestPolicy_RetriesTransientErrors(t *testing.T) {
+	slept := withRecordedSleeps(t)
+	policy := NewPolicy(3)
+
+	calls := 0
+	err := policy.Do(context.Background(), func(attempt int) error {
+		calls++
+		if attempt < 3 {
+			return &HTTPError{Service: "test", StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Len(t, *slept, 2)
+	for i, d := range *slept {
+		backoff := DefaultBaseDelay << i
+		assert.GreaterOrEqual(t, d, backoff/2)
+		assert.Less(t, d, backoff)
+	}
+}
+
+func TestPolicy_StopsAfterMaxRetries(t *testing.T) {
+	withRecordedSleeps(t)
+	policy := NewPolicy(2)
+
+	calls := 0
+	err := policy.Do(context.Background(), func(attempt int) error {
+		calls++
+		return fmt.Errorf("failed to make request: %w", &url.Error{Op: "Post", URL: "http://example", Err: errors.New("connection reset")})
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestPolicy_DoesNotRetryClientErrors(t *testing.T) {
+	slept := withRecordedSleeps(t)
+	policy := NewPolicy(3)
+
+	calls := 0
+	err := policy.Do(context.Background(), func(attempt int) error {
+		calls++
+		return &HTTPError{Service: "test", StatusCode: http.StatusBadRequest}
+	})
+
+	assert.EqualError(t, err, "test returned status 400")
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, *slept)
+}
+
+func TestPolicy_HonorsRetryAfter(t *testing.T) {
+	slept := withRecordedSleeps(t)
+	policy := NewPolicy(1)
+
+	_ = policy.Do(context.Background(), func(attempt int) error {
+		return &HTTPError{Service: "test", StatusCode: http.StatusTooManyRequests, RetryAfter: 7 * time.Second}
+	})
+
+	assert.Equal(t, []time.Duration{7 * time.Second}, *slept)
+}
+
+func TestPolicy_StopsWhenContextDone(t *testing.T) {
+	policy := Policy{MaxRetries: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	err := policy.Do(ctx, func(attempt int) error {
+		calls++
+		cancel()
+		return &HTTPError{Service: "test", StatusCode: http.StatusServiceUnavailable}
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"too many requests", &HTTPError{StatusCode: http.StatusTooManyRequests}, true},
+		{"server error", &HTTPError{StatusCode: http.StatusBadGateway}, true},
+		{"not found", &HTTPError{StatusCode: http.StatusNotFound}, false},
+		{"network", &url.Error{Op: "Get", URL: "http://example", Err: errors.New("dial tcp: refused")}, true},
+		{"cancelled", &url.Error{Op: "Get", URL: "http://example", Err: context.Canceled}, false},
+		{"decode", errors.New("failed to decode response"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 120*time.Second, ParseRetryAfter("120"))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("soon"))
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	wait := ParseRetryAfter(date)
+	assert.Greater(t, wait, 50*time.Second)
+	assert.LessOrEqual(t, wait, time.Minute)
+}
+
+func TestCounter(t *testing.T) {
+	var nilCounter *Counter
+	nilCounter.Add(1)
+	assert.Equal(t, 0, nilCounter.Attempts())
+
+	counter := &Counter{}
+	counter.Add(2)
+	counter.Add(1)
+	assert.Equal(t, 3, counter.Attempts())
+}
@@ -0,0 +1,273 @@
+// Package rtf converts Rich Text Format documents to plain text.
+// Formatting is dropped; paragraphs, line breaks, tabs and table rows
+// are kept, and destinations that hold no body text (font tables,
+// pictures, headers, field instructions) are skipped.
+package rtf
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+var (
+	ErrInvalid = errors.New("not a valid RTF file")
+	ErrNoText  = errors.New("RTF contains no text")
+)
+
+// maxGroupDepth guards against runaway nesting in malformed files
+const maxGroupDepth = 256
+
+// skippedDestinations hold metadata or binary data rather than body text
+var skippedDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true,
+	"pict": true, "object": true, "header": true, "headerl": true,
+	"headerr": true, "headerf": true, "footer": true, "footerl": true,
+	"footerr": true, "footerf": true, "fldinst": true, "listtable": true,
+	"listoverridetable": true, "rsidtbl": true, "generator": true,
+	"themedata": true, "colorschememapping": true, "latentstyles": true,
+	"datastore": true, "xmlnstbl": true, "pgdsctbl": true, "filetbl": true,
+	"revtbl": true, "footnote": true, "annotation": true,
+}
+
+// specialCharacters maps control words to the text they stand for
+var specialCharacters = map[string]string{
+	"par": "\n", "line": "\n", "sect": "\n", "page": "\n", "row": "\n",
+	"tab": "\t", "cell": " | ", "emdash": "—", "endash": "–",
+	"bullet": "•", "lquote": "‘", "rquote": "’", "ldblquote": "“",
+	"rdblquote": "”", "emspace": " ", "enspace": " ", "qmspace": " ",
+}
+
+// group is the state saved and restored around each {...} group
+type group struct {
+	skip   bool
+	ucSkip int
+}
+
+type converter struct {
+	data  []byte
+	pos   int
+	out   strings.Builder
+	state group
+	stack []group
+
+	// pending counts fallback characters to drop after a \u escape
+	pending int
+	// surrogate holds the high half of a UTF-16 pair written as two \u escapes
+	surrogate rune
+}
+
+// ExtractText converts an RTF document to plain text
+func ExtractText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(`{\rtf`)) {
+		return "", ErrInvalid
+	}
+
+	c := &converter{data: data, state: group{ucSkip: 1}}
+	c.run()
+
+	text := cleanText(c.out.String())
+	if text == "" {
+		return "", ErrNoText
+	}
+	return text, nil
+}
+
+func (c *converter) run() {
+	for c.pos < len(c.data) {
+		ch := c.data[c.pos]
+		switch ch {
+		case '{':
+			c.pos++
+			if len(c.stack) < maxGroupDepth {
+				c.stack = append(c.stack, c.state)
+			}
+			// An ignorable destination \* is skipped as a whole
+			if bytes.HasPrefix(c.data[c.pos:], []byte(`\*`)) {
+				c.state.skip = true
+			}
+		case '}':
+			c.pos++
+			if n := len(c.stack); n > 0 {
+				c.state = c.stack[n-1]
+				c.stack = c.stack[:n-1]
+			}
+			c.pending = 0
+		case '\\':
+			c.control()
+		case '\r', '\n':
+			c.pos++
+		default:
+			c.pos++
+			c.writeByte(ch)
+		}
+	}
+}
+
+// control handles a control word or control symbol starting at a backslash
+func (c *converter) control() {
+	c.pos++ // skip '\'
+	if c.pos >= len(c.data) {
+		return
+	}
+
+	ch := c.data[c.pos]
+	if !isLetter(ch) {
+		c.pos++
+		switch ch {
+		case '\'':
+			if c.pos+2 <= len(c.data) {
+				if v, err := strconv.ParseUint(string(c.data[c.pos:c.pos+2]), 16, 8); err == nil {
+					c.writeByte(byte(v))
+				}
+				c.pos += 2
+			}
+		case '\\', '{', '}':
+			c.writeByte(ch)
+		case '~':
+			c.writeText(" ")
+		case '_':
+			c.writeText("-")
+		case '\r', '\n':
+			c.writeText("\n")
+		}
+		return
+	}
+
+	start := c.pos
+	for c.pos < len(c.data) && isLetter(c.data[c.pos]) {
+		c.pos++
+	}
+	word := string(c.data[start:c.pos])
+
+	paramStart := c.pos
+	if c.pos < len(c.data) && c.data[c.pos] == '-' {
+		c.pos++
+	}
+	for c.pos < len(c.data) && c.data[c.pos] >= '0' && c.data[c.pos] <= '9' {
+		c.pos++
+	}
+	param, hasParam := 0, false
+	if c.pos > paramStart {
+		if v, err := strconv.Atoi(string(c.data[paramStart:c.pos])); err == nil {
+			param, hasParam = v, true
+		}
+	}
+
+	// A single space delimits the control word and is not part of the text
+	if c.pos < len(c.data) && c.data[c.pos] == ' ' {
+		c.pos++
+	}
+
+	c.word(word, param, hasParam)
+}
+
+func (c *converter) word(word string, param int, hasParam bool) {
+	switch {
+	case word == "bin":
+		// Binary data follows, skip it regardless of the destination
+		if hasParam && param > 0 {
+			c.pos += param
+		}
+	case word == "uc":
+		if hasParam && param >= 0 {
+			c.state.ucSkip = param
+		}
+	case word == "u":
+		if !hasParam {
+			return
+		}
+		if param < 0 {
+			param += 0x10000
+		}
+		c.writeRune(rune(param))
+		c.pending = c.state.ucSkip
+	case skippedDestinations[word]:
+		c.state.skip = true
+	default:
+		if text, ok := specialCharacters[word]; ok {
+			c.writeText(text)
+		}
+	}
+}
+
+func (c *converter) writeText(s string) {
+	if c.state.skip {
+		return
+	}
+	c.pending = 0
+	c.out.WriteString(s)
+}
+
+func (c *converter) writeRune(r rune) {
+	if c.state.skip {
+		return
+	}
+
+	if utf16.IsSurrogate(r) {
+		if c.surrogate != 0 {
+			r = utf16.DecodeRune(c.surrogate, r)
+			c.surrogate = 0
+		} else {
+			c.surrogate = r
+			return
+		}
+	}
+	c.out.WriteRune(r)
+}
+
+// writeByte writes a character from the document code page
+func (c *converter) writeByte(b byte) {
+	if c.pending > 0 {
+		c.pending--
+		return
+	}
+	if c.state.skip {
+		return
+	}
+	c.out.WriteRune(cp1252[b])
+}
+
+func isLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+// cleanText trims trailing spaces, collapses runs of blank lines and
+// drops the separator left after the last cell of a table row
+func cleanText(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	blank := 0
+	for _, line := range lines {
+		line = strings.TrimSuffix(strings.TrimRight(line, " \t"), " |")
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			blank++
+			if blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// cp1252 maps Windows-1252 bytes, the code page of most RTF files, to runes
+var cp1252 = func() [256]rune {
+	var table [256]rune
+	for i := range table {
+		table[i] = rune(i)
+	}
+	high := []rune{
+		0x20ac, 0x81, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+		0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0x8d, 0x017d, 0x8f,
+		0x90, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+		0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0x9d, 0x017e, 0x0178,
+	}
+	copy(table[0x80:0xa0], high)
+	return table
+}()
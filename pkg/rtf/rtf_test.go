@@ -0,0 +1,43 @@
+package rtf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractText(t *testing.T) {
+	doc := `{\rtf1\ansi\ansicpg1252\deff0{\fonttbl{\f0\fswiss Arial;}}{\colortbl;\red0\green0\blue0;}
+{\*\generator Riched20 10.0.19041}\viewkind4\uc1
+\pard\b Rapat Senin\b0\par
+Caf\'e9 jam 9\tab ruang \{A\}\par
+\u8226? Kirim laporan\line\u8211? revisi\par
+{\field{\*\fldinst HYPERLINK "https://example.com"}{\fldrslt tautan}}\par
+\trowd\cellx1000\cellx2000\intbl Tugas\cell PIC\cell\row
+\intbl Deploy\cell Rina\cell\row
+{\pict\pngblip 89504e47}
+}`
+
+	text, err := ExtractText([]byte(doc))
+	require.NoError(t, err)
+
+	expected := "Rapat Senin\n" +
+		"Café jam 9\truang {A}\n" +
+		"• Kirim laporan\n" +
+		"– revisi\n" +
+		"tautan\n" +
+		"Tugas | PIC\n" +
+		"Deploy | Rina"
+	assert.Equal(t, expected, text)
+}
+
+func TestExtractText_Invalid(t *testing.T) {
+	_, err := ExtractText([]byte("plain text"))
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestExtractText_Empty(t *testing.T) {
+	_, err := ExtractText([]byte(`{\rtf1\ansi{\fonttbl{\f0 Arial;}}\par}`))
+	assert.ErrorIs(t, err, ErrNoText)
+}
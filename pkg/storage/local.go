@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores objects on the local filesystem, for development and tests
+type Local struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocal creates a store that writes objects below dir. Object URLs
+// start with baseURL when it is set and are file:// URLs otherwise.
+func NewLocal(dir, baseURL string) (*Local, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage directory: %w", err)
+	}
+
+	if err := os.MkdirAll(abs, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	return &Local{
+		dir:     abs,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+// Put writes data to key and returns its URL
+func (l *Local) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(l.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+
+	if l.baseURL != "" {
+		return l.baseURL + "/" + key, nil
+	}
+	return "file://" + filepath.ToSlash(path), nil
+}
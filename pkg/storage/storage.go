@@ -0,0 +1,21 @@
+// Package storage keeps copies of uploaded inputs so todos can link back
+// to the document they came from
+package storage
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// ErrInvalidKey is returned for object keys that are empty or escape the store
+var ErrInvalidKey = errors.New("invalid object key")
+
+// cleanKey validates a slash separated object key
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != key || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
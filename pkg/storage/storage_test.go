@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo-agent-backend/pkg/retry"
+	"todo-agent-backend/pkg/supabase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal_Put(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocal(dir, "https://files.example.com/")
+	require.NoError(t, err)
+
+	url, err := store.Put(context.Background(), "user-1/job-1.txt", []byte("Kirim laporan"), "text/plain")
+	require.NoError(t, err)
+	assert.Equal(t, "https://files.example.com/user-1/job-1.txt", url)
+
+	data, err := os.ReadFile(filepath.Join(dir, "user-1", "job-1.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "Kirim laporan", string(data))
+}
+
+func TestLocal_PutFileURL(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocal(dir, "")
+	require.NoError(t, err)
+
+	url, err := store.Put(context.Background(), "job-1.pdf", []byte("%PDF-"), "application/pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "file://"+filepath.ToSlash(filepath.Join(dir, "job-1.pdf")), url)
+}
+
+func TestLocal_RejectsInvalidKeys(t *testing.T) {
+	store, err := NewLocal(t.TempDir(), "")
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "../escape.txt", "a/../../escape.txt", "/abs.txt", `a\b.txt`} {
+		_, err := store.Put(context.Background(), key, []byte("x"), "text/plain")
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
+
+func TestSupabase_Put(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/storage/v1/object/sources/user-1/job-1.pdf", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/pdf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "true", r.Header.Get("x-upsert"))
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := supabase.NewClient(server.URL, "test-key", time.Second, retry.NewPolicy(0))
+
+	url, err := NewSupabase(client, "sources", false).Put(context.Background(), "user-1/job-1.pdf", []byte("%PDF-"), "application/pdf")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/storage/v1/object/authenticated/sources/user-1/job-1.pdf", url)
+	assert.Equal(t, "%PDF-", string(body))
+
+	url, err = NewSupabase(client, "sources", true).Put(context.Background(), "user-1/job-1.pdf", []byte("%PDF-"), "application/pdf")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/storage/v1/object/public/sources/user-1/job-1.pdf", url)
+}
+
+func TestSupabase_PutError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := supabase.NewClient(server.URL, "test-key", time.Second, retry.NewPolicy(0))
+
+	_, err := NewSupabase(client, "missing", false).Put(context.Background(), "job-1.txt", []byte("x"), "text/plain")
+	var httpErr *retry.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+}
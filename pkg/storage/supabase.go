@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+
+	"todo-agent-backend/pkg/supabase"
+)
+
+// Supabase stores objects in a Supabase Storage bucket
+type Supabase struct {
+	client *supabase.Client
+	bucket string
+	public bool
+}
+
+// NewSupabase creates a store for bucket. Set public when the bucket
+// allows anonymous downloads so returned URLs work without a key.
+func NewSupabase(client *supabase.Client, bucket string, public bool) *Supabase {
+	return &Supabase{
+		client: client,
+		bucket: bucket,
+		public: public,
+	}
+}
+
+// Put uploads data to key and returns its URL
+func (s *Supabase) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.client.UploadObject(ctx, s.bucket, key, data, contentType); err != nil {
+		return "", err
+	}
+
+	return s.client.ObjectURL(s.bucket, key, s.public), nil
+}
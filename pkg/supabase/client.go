@@ -2,31 +2,52 @@ package supabase
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"todo-agent-backend/internal/models"
+	"todo-agent-backend/pkg/retry"
+
+	"github.com/google/uuid"
 )
 
 type Client struct {
-	url        string
-	key        string
-	httpClient *http.Client
+	url         string
+	key         string
+	httpClient  *http.Client
+	retryPolicy retry.Policy
+	counter     *retry.Counter
 }
 
-func NewClient(url, key string) *Client {
+const DefaultTimeout = 30 * time.Second
+
+func NewClient(url, key string, timeout time.Duration, retryPolicy retry.Policy) *Client {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
 	return &Client{
 		url: url,
 		key: key,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: timeout,
 		},
+		retryPolicy: retryPolicy,
 	}
 }
 
-func (c *Client) InsertTodo(todo *models.Todo) error {
+// WithRetryCounter returns a copy of the client that records every request attempt in counter
+func (c *Client) WithRetryCounter(counter *retry.Counter) *Client {
+	clone := *c
+	clone.counter = counter
+	return &clone
+}
+
+func (c *Client) InsertTodo(ctx context.Context, todo *models.Todo) error {
 	url := fmt.Sprintf("%s/rest/v1/todos", c.url)
 
 	jsonData, err := json.Marshal(todo)
@@ -34,30 +55,10 @@ func (c *Client) InsertTodo(todo *models.Todo) error {
 		return fmt.Errorf("failed to marshal todo: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("apikey", c.key)
-	req.Header.Set("Authorization", "Bearer "+c.key)
-	req.Header.Set("Prefer", "return=minimal")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("supabase returned status %d", resp.StatusCode)
-	}
-
-	return nil
+	return c.insert(ctx, url, jsonData)
 }
 
-func (c *Client) InsertTodos(todos []models.Todo) error {
+func (c *Client) InsertTodos(ctx context.Context, todos []models.Todo) error {
 	if len(todos) == 0 {
 		return nil
 	}
@@ -69,54 +70,161 @@ func (c *Client) InsertTodos(todos []models.Todo) error {
 		return fmt.Errorf("failed to marshal todos: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
+	return c.insert(ctx, url, jsonData)
+}
+
+func (c *Client) GetTodosByUserID(ctx context.Context, userID string) ([]models.Todo, error) {
+	return c.selectTodos(ctx, fmt.Sprintf("user_id=eq.%s&order=created_at.desc", url.QueryEscape(userID)))
+}
+
+// GetOpenTodosByUserID retrieves the todos of a user that are not completed
+func (c *Client) GetOpenTodosByUserID(ctx context.Context, userID string) ([]models.Todo, error) {
+	return c.selectTodos(ctx, fmt.Sprintf("user_id=eq.%s&completed=is.false&order=created_at.desc", url.QueryEscape(userID)))
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("apikey", c.key)
-	req.Header.Set("Authorization", "Bearer "+c.key)
-	req.Header.Set("Prefer", "return=minimal")
+// UpdateTodo changes the fields set in patch on the todo with the given ID
+func (c *Client) UpdateTodo(ctx context.Context, id uuid.UUID, patch models.TodoPatch) error {
+	endpoint := fmt.Sprintf("%s/rest/v1/todos?id=eq.%s", c.url, id)
 
-	resp, err := c.httpClient.Do(req)
+	jsonData, err := json.Marshal(patch)
 	if err != nil {
-		return fmt.Errorf("failed to make request: %w", err)
+		return fmt.Errorf("failed to marshal todo patch: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("supabase returned status %d", resp.StatusCode)
-	}
+	return c.retryPolicy.Do(ctx, func(attempt int) error {
+		c.counter.Add(1)
 
-	return nil
-}
+		req, err := http.NewRequestWithContext(ctx, "PATCH", endpoint, bytes.NewReader(jsonData))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
 
-func (c *Client) GetTodosByUserID(userID string) ([]models.Todo, error) {
-	url := fmt.Sprintf("%s/rest/v1/todos?user_id=eq.%s&order=created_at.desc", c.url, userID)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("apikey", c.key)
+		req.Header.Set("Authorization", "Bearer "+c.key)
+		req.Header.Set("Prefer", "return=minimal")
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to make request: %w", err)
+		}
+		defer resp.Body.Close()
 
-	req.Header.Set("apikey", c.key)
-	req.Header.Set("Authorization", "Bearer "+c.key)
+		if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+			return retry.NewHTTPError("supabase", resp)
+		}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
+		return nil
+	})
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("supabase returned status %d", resp.StatusCode)
-	}
+// selectTodos reads the todos matching a PostgREST query string, retrying transient failures
+func (c *Client) selectTodos(ctx context.Context, query string) ([]models.Todo, error) {
+	endpoint := fmt.Sprintf("%s/rest/v1/todos?%s", c.url, query)
 
 	var todos []models.Todo
-	if err := json.NewDecoder(resp.Body).Decode(&todos); err != nil {
-		return nil, fmt.Errorf("failed to decode todos: %w", err)
+	err := c.retryPolicy.Do(ctx, func(attempt int) error {
+		c.counter.Add(1)
+
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("apikey", c.key)
+		req.Header.Set("Authorization", "Bearer "+c.key)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to make request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return retry.NewHTTPError("supabase", resp)
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&todos); err != nil {
+			return fmt.Errorf("failed to decode todos: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return todos, nil
 }
+
+// insert posts rows to a table, retrying transient failures.
+// Rows carry client-generated IDs, so duplicates from a retried request
+// that already succeeded are ignored instead of failing the insert.
+func (c *Client) insert(ctx context.Context, url string, body []byte) error {
+	return c.retryPolicy.Do(ctx, func(attempt int) error {
+		c.counter.Add(1)
+
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("apikey", c.key)
+		req.Header.Set("Authorization", "Bearer "+c.key)
+		req.Header.Set("Prefer", "return=minimal,resolution=ignore-duplicates")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to make request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusCreated {
+			return retry.NewHTTPError("supabase", resp)
+		}
+
+		return nil
+	})
+}
+
+// UploadObject stores data in a Supabase Storage bucket, replacing any
+// object already at path
+func (c *Client) UploadObject(ctx context.Context, bucket, path string, data []byte, contentType string) error {
+	endpoint := fmt.Sprintf("%s/storage/v1/object/%s/%s", c.url, bucket, path)
+
+	return c.retryPolicy.Do(ctx, func(attempt int) error {
+		c.counter.Add(1)
+
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("apikey", c.key)
+		req.Header.Set("Authorization", "Bearer "+c.key)
+		req.Header.Set("x-upsert", "true")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to make request: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return retry.NewHTTPError("supabase storage", resp)
+		}
+
+		return nil
+	})
+}
+
+// ObjectURL returns the URL of an object in a Supabase Storage bucket.
+// Objects in private buckets need an authenticated request to download.
+func (c *Client) ObjectURL(bucket, path string, public bool) string {
+	if public {
+		return fmt.Sprintf("%s/storage/v1/object/public/%s/%s", c.url, bucket, path)
+	}
+	return fmt.Sprintf("%s/storage/v1/object/authenticated/%s/%s", c.url, bucket, path)
+}
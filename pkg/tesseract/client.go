@@ -0,0 +1,99 @@
+package tesseract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPath     = "tesseract"
+	DefaultLanguage = "eng"
+	DefaultTimeout  = 60 * time.Second
+)
+
+// ErrTimeout is returned when recognition takes longer than the client timeout
+var ErrTimeout = errors.New("tesseract timed out")
+
+// Client runs the Tesseract command line tool to recognize text in images
+type Client struct {
+	path     string
+	language string
+	tempDir  string
+	timeout  time.Duration
+}
+
+func NewClient(path, language, tempDir string, timeout time.Duration) *Client {
+	if path == "" {
+		path = DefaultPath
+	}
+
+	if language == "" {
+		language = DefaultLanguage
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Client{
+		path:     path,
+		language: language,
+		tempDir:  tempDir,
+		timeout:  timeout,
+	}
+}
+
+// Recognize returns the text found in an image. format is the image file
+// extension without the dot, e.g. "png" or "jpeg".
+func (c *Client) Recognize(ctx context.Context, image []byte, format string) (string, error) {
+	if c.tempDir != "" {
+		if err := os.MkdirAll(c.tempDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create OCR temp directory: %w", err)
+		}
+	}
+
+	input, err := os.CreateTemp(c.tempDir, "ocr-*."+format)
+	if err != nil {
+		return "", fmt.Errorf("failed to create OCR input file: %w", err)
+	}
+	defer os.Remove(input.Name())
+
+	if _, err := input.Write(image); err != nil {
+		input.Close()
+		return "", fmt.Errorf("failed to write OCR input file: %w", err)
+	}
+	if err := input.Close(); err != nil {
+		return "", fmt.Errorf("failed to write OCR input file: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.path, input.Name(), "stdout", "-l", c.language)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Do not wait on pipes held open by children once the process is killed
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%w after %s", ErrTimeout, c.timeout)
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("tesseract failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("tesseract failed: %w", err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
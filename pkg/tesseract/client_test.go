@@ -0,0 +1,62 @@
+package tesseract
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeStub creates an executable shell script that stands in for tesseract
+func writeStub(t *testing.T, script string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "tesseract")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755))
+	return path
+}
+
+func TestRecognize(t *testing.T) {
+	// The stub echoes its arguments and the image contents
+	stub := writeStub(t, `echo "args: $2 $3 $4"; cat "$1"`)
+	tempDir := t.TempDir()
+
+	client := NewClient(stub, "ind+eng", tempDir, time.Second)
+	text, err := client.Recognize(context.Background(), []byte("Beli susu\n"), "png")
+	require.NoError(t, err)
+	assert.Equal(t, "args: stdout -l ind+eng\nBeli susu", text)
+
+	// The input file is removed afterwards
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRecognize_Failure(t *testing.T) {
+	stub := writeStub(t, `echo "Failed loading language 'xyz'" >&2; exit 1`)
+
+	client := NewClient(stub, "xyz", t.TempDir(), time.Second)
+	_, err := client.Recognize(context.Background(), []byte("image"), "png")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed loading language 'xyz'")
+}
+
+func TestRecognize_Timeout(t *testing.T) {
+	stub := writeStub(t, `exec sleep 5`)
+
+	client := NewClient(stub, "eng", t.TempDir(), 100*time.Millisecond)
+	start := time.Now()
+	_, err := client.Recognize(context.Background(), []byte("image"), "png")
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
+
+func TestRecognize_MissingBinary(t *testing.T) {
+	client := NewClient(filepath.Join(t.TempDir(), "missing"), "eng", t.TempDir(), time.Second)
+	_, err := client.Recognize(context.Background(), []byte("image"), "png")
+	assert.Error(t, err)
+}